@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/ldap.v2"
+)
+
+// SourceMetric is a metric computed from the full result set of a search rather
+// than from a single attribute of a single entry.
+type SourceMetric interface {
+	Collect(ctx *scrapeContext, entries []*ldap.Entry) ([]prometheus.Metric, error)
+	GetDesc() *prometheus.Desc
+	// attributes that must be requested from the server for this metric.
+	GetAttributes() []string
+}
+
+type AggregateMetric struct {
+	Desc            *prometheus.Desc
+	kind            string
+	attribute       string
+	sourceLabels    []string
+	groupAttributes []string
+	buckets         []float64
+	converter       *valueConverter
+}
+
+func NewAggregateMetric(metric_name string, kind string, attribute string, source_labels []string, group_attributes []string, labels []string, constant_labels map[string]string, converter *valueConverter, buckets []float64, help string) *AggregateMetric {
+	return &AggregateMetric{
+		kind:            kind,
+		converter:       converter,
+		attribute:       attribute,
+		sourceLabels:    source_labels,
+		groupAttributes: group_attributes,
+		buckets:         buckets,
+		Desc: prometheus.NewDesc(
+			metric_name,
+			help,
+			labels,
+			prometheus.Labels(constant_labels),
+		),
+	}
+}
+
+type aggregateGroup struct {
+	labels    []string
+	count     float64
+	sum       float64
+	min       float64
+	max       float64
+	histogram *histogramData
+}
+
+func (a *AggregateMetric) newGroup(labels []string) *aggregateGroup {
+	group := &aggregateGroup{labels: labels, min: math.Inf(1), max: math.Inf(-1)}
+	if a.kind == "histogram" {
+		group.histogram = newHistogramData(a.buckets, labels)
+	}
+	return group
+}
+
+// groupLabels returns the source's labels followed by the values of the group_by attributes.
+func (a *AggregateMetric) groupLabels(ctx *scrapeContext, entry *ldap.Entry) ([]string, error) {
+	var labels []string
+	for _, label := range a.sourceLabels {
+		labels = append(labels, ctx.sourceLabels[label])
+	}
+	for _, attr := range a.groupAttributes {
+		values := entry.GetAttributeValues(attr)
+		if len(values) > 1 {
+			return nil, fmt.Errorf("attribute %s is a group_by attribute but has multiple values for %s: %s", attr, entry.DN, values)
+		}
+		// entries lacking the attribute are grouped together under an empty label.
+		value := ""
+		if len(values) == 1 {
+			value = values[0]
+		}
+		labels = append(labels, value)
+	}
+	return labels, nil
+}
+
+func (a *AggregateMetric) Collect(ctx *scrapeContext, entries []*ldap.Entry) ([]prometheus.Metric, error) {
+	var order []string
+	groups := make(map[string]*aggregateGroup)
+	if len(a.groupAttributes) == 0 {
+		// always export ungrouped counts and sums, even if nothing matched.
+		labels, err := a.groupLabels(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.Join(labels, "\x00")
+		order = append(order, key)
+		groups[key] = a.newGroup(labels)
+	}
+
+	for _, entry := range entries {
+		labels, err := a.groupLabels(ctx, entry)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.Join(labels, "\x00")
+		group, ok := groups[key]
+		if !ok {
+			group = a.newGroup(labels)
+			groups[key] = group
+			order = append(order, key)
+		}
+		if a.kind == "count" {
+			group.count++
+			continue
+		}
+		for _, value := range entry.GetAttributeValues(a.attribute) {
+			x, err := a.converter.convert(ctx, value)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s of %s couldn't be parsed: %s", a.attribute, entry.DN, err)
+			}
+			group.count++
+			group.sum += x
+			group.min = math.Min(group.min, x)
+			group.max = math.Max(group.max, x)
+			if group.histogram != nil {
+				group.histogram.observe(x)
+			}
+		}
+	}
+
+	var metrics []prometheus.Metric
+	for _, key := range order {
+		group := groups[key]
+		if group.histogram != nil {
+			metric, err := group.histogram.metric(a.Desc)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, metric)
+			continue
+		}
+		var value float64
+		switch a.kind {
+		case "count":
+			value = group.count
+		case "sum":
+			value = group.sum
+		default:
+			// min, max, and avg of nothing isn't meaningful; skip the group.
+			if group.count == 0 {
+				continue
+			}
+			switch a.kind {
+			case "min":
+				value = group.min
+			case "max":
+				value = group.max
+			case "avg":
+				value = group.sum / group.count
+			}
+		}
+		metric, err := prometheus.NewConstMetric(a.Desc, prometheus.GaugeValue, value, group.labels...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed creating metric %s: %s", a.Desc, err)
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+func (a *AggregateMetric) GetDesc() *prometheus.Desc {
+	return a.Desc
+}
+
+func (a *AggregateMetric) GetAttributes() []string {
+	attrs := append([]string{}, a.groupAttributes...)
+	if a.attribute != "" {
+		attrs = append(attrs, a.attribute)
+	}
+	return attrs
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/ldap.v2"
+)
+
+var (
+	assertionSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "assertion", "success"),
+		"Whether the assertion held.",
+		[]string{"name"}, nil,
+	)
+	assertionObservedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "assertion", "observed_value"),
+		"What the assertion observed; the entry count, value count, matching entry count, or compare result.",
+		[]string{"name"}, nil,
+	)
+)
+
+// rangeConfig bounds an observed count; either end may be omitted.
+type rangeConfig struct {
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (r *rangeConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain rangeConfig
+
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	if err := checkOverflow(r.X, "range"); err != nil {
+		return err
+	}
+	if r.Min == nil && r.Max == nil {
+		return fmt.Errorf("range requires min, max, or both")
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("range min %v is above max %v", *r.Min, *r.Max)
+	}
+	return nil
+}
+
+func (r *rangeConfig) contains(value float64) bool {
+	return (r.Min == nil || value >= *r.Min) && (r.Max == nil || value <= *r.Max)
+}
+
+type compareConfig struct {
+	DN        dnString `yaml:"dn"`
+	Attribute string   `yaml:"attribute"`
+	Value     string   `yaml:"value"`
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+// assertionConfig checks that a source's search returns what's expected, for example that a
+// service account exists or a group has at least some number of members.  Exactly one of
+// count, attribute, or compare is given:
+// count bounds how many entries the search returns.
+// attribute with values bounds how many values of the attribute the entries have in total;
+// with equals or matches, every entry must have a value equal to or fully matching the regex,
+// and at least one entry must be returned.
+// compare issues an LDAP compare, which is independent of the search.
+// The search's base or the compare's dn not existing counts as no entries or a false compare
+// respectively, and an assertion that fails to evaluate is reported as not holding.
+type assertionConfig struct {
+	Count     *rangeConfig   `yaml:"count"`
+	Attribute string         `yaml:"attribute"`
+	Values    *rangeConfig   `yaml:"values"`
+	Equals    *string        `yaml:"equals"`
+	Matches   string         `yaml:"matches"`
+	Compare   *compareConfig `yaml:"compare"`
+
+	matches *regexp.Regexp
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (ac *assertionConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain assertionConfig
+
+	if err := unmarshal((*plain)(ac)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(ac.X, "assertion"); err != nil {
+		return err
+	}
+
+	kinds := 0
+	for _, given := range []bool{ac.Count != nil, ac.Attribute != "", ac.Compare != nil} {
+		if given {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("assertions require exactly one of count, attribute, or compare")
+	}
+
+	checks := 0
+	for _, given := range []bool{ac.Values != nil, ac.Equals != nil, ac.Matches != ""} {
+		if given {
+			checks++
+		}
+	}
+	if ac.Attribute == "" && checks != 0 {
+		return fmt.Errorf("values, equals, and matches require attribute")
+	}
+	if ac.Attribute != "" && checks != 1 {
+		return fmt.Errorf("attribute assertions require exactly one of values, equals, or matches")
+	}
+	if ac.Matches != "" {
+		re, err := regexp.Compile("^(?:" + ac.Matches + ")$")
+		if err != nil {
+			return fmt.Errorf("assertion matches %s is malformed: %s", ac.Matches, err)
+		}
+		ac.matches = re
+	}
+
+	if ac.Compare != nil {
+		if err := checkOverflow(ac.Compare.X, "compare"); err != nil {
+			return err
+		}
+		if ac.Compare.DN == "" || ac.Compare.Attribute == "" {
+			return fmt.Errorf("compare requires dn and attribute")
+		}
+		if isSearchTemplate(string(ac.Compare.DN)) {
+			return fmt.Errorf("compare dn cannot be a template")
+		}
+	}
+	return nil
+}
+
+// AssertionMetric reports whether an assertion held, and what it observed.  Since every
+// assertion shares the metrics, names must be unique across sources and constant labels
+// aren't applied.
+type AssertionMetric struct {
+	name   string
+	config *assertionConfig
+}
+
+func NewAssertionMetric(name string, config *assertionConfig) *AssertionMetric {
+	return &AssertionMetric{name: name, config: config}
+}
+
+func (a *AssertionMetric) Collect(ctx *scrapeContext, entries []*ldap.Entry) ([]prometheus.Metric, error) {
+	success, observed, err := a.evaluate(ctx, entries)
+	if err != nil {
+		// an assertion that can't be evaluated didn't hold; the source's other metrics are unaffected.
+		log.Errorf("assertion %s failed to evaluate: %s", a.name, err)
+		success, observed = false, 0
+	}
+	success_value := float64(0)
+	if success {
+		success_value = 1
+	}
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(assertionSuccessDesc, prometheus.GaugeValue, success_value, a.name),
+		prometheus.MustNewConstMetric(assertionObservedDesc, prometheus.GaugeValue, observed, a.name),
+	}, nil
+}
+
+func (a *AssertionMetric) evaluate(ctx *scrapeContext, entries []*ldap.Entry) (bool, float64, error) {
+	c := a.config
+	switch {
+	case c.Count != nil:
+		observed := float64(len(entries))
+		return c.Count.contains(observed), observed, nil
+	case c.Compare != nil:
+		matched, err := ctx.conn.Compare(string(c.Compare.DN), c.Compare.Attribute, c.Compare.Value)
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return false, 0, nil
+		}
+		if err != nil {
+			return false, 0, err
+		}
+		if matched {
+			return true, 1, nil
+		}
+		return false, 0, nil
+	case c.Values != nil:
+		observed := float64(0)
+		for _, entry := range entries {
+			observed += float64(len(entry.GetAttributeValues(c.Attribute)))
+		}
+		return c.Values.contains(observed), observed, nil
+	}
+	matching := 0
+	for _, entry := range entries {
+		for _, value := range entry.GetAttributeValues(c.Attribute) {
+			if (c.Equals != nil && value == *c.Equals) || (c.matches != nil && c.matches.MatchString(value)) {
+				matching++
+				break
+			}
+		}
+	}
+	return len(entries) != 0 && matching == len(entries), float64(matching), nil
+}
+
+func (a *AssertionMetric) GetDesc() *prometheus.Desc {
+	return assertionSuccessDesc
+}
+
+// GetDescs includes the observed value's desc, which GetDesc can't return.
+func (a *AssertionMetric) GetDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{assertionSuccessDesc, assertionObservedDesc}
+}
+
+func (a *AssertionMetric) GetAttributes() []string {
+	if a.config.Attribute == "" {
+		return nil
+	}
+	return []string{a.config.Attribute}
+}
@@ -29,10 +29,10 @@ var assets = func() http.FileSystem {
 		},
 		"/definitions/389.yaml": &vfsgen۰CompressedFileInfo{
 			name:             "389.yaml",
-			modTime:          time.Date(2020, 1, 7, 0, 17, 1, 200216259, time.UTC),
-			uncompressedSize: 6842,
+			modTime:          time.Date(2026, 10, 18, 12, 45, 31, 338693795, time.UTC),
+			uncompressedSize: 8278,
 
-			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xc4\x99\x5f\x6f\x1b\x37\x12\xc0\xdf\xfd\x29\x06\x4a\xd1\xd8\x39\xcb\x58\xc9\x91\xff\x2c\xaa\x87\xe2\x5c\xe0\x0a\x24\x45\xd1\xa2\xf7\xe0\x24\x10\x46\xe4\x48\xe2\x95\x4b\xae\xc9\x59\x45\xaa\x9a\xef\x7e\xe0\x6a\x25\xad\xfe\xad\x68\x27\x40\x13\x20\xb6\xa8\x1f\x87\x9c\xe1\xcc\x70\x86\x69\x83\xc1\x8c\x52\xc8\xac\x51\x6c\xdd\x19\x80\x27\x74\x62\x92\xc2\x6b\x61\xfa\xef\x97\xa3\xaf\xcf\x00\x46\x4a\x33\xb9\x14\x5e\x9f\x0b\xd3\xaf\xe8\x8b\xf0\x05\x32\x3b\x35\x2c\x98\x7c\x7a\x06\x00\x90\x11\x3b\x25\xaa\x0f\x00\x3c\x71\x84\x72\xfd\x11\x80\xe7\x39\xa5\x30\xc6\x62\x4c\xd5\x98\x28\x9c\x23\xc3\xc2\x1a\x43\x82\x95\x35\xdf\x90\x66\xcb\xa8\x5f\x2c\x19\x39\xc3\x59\x84\x06\x1b\x2a\x27\x17\xe6\x4f\x14\x37\x4e\x90\x8c\x43\x4d\x5e\xfd\x45\x4d\x54\x90\xf8\x19\x15\x93\x6b\x14\x66\x73\xaf\x8c\x62\x85\x4c\x72\x97\x13\xb6\x30\x4c\x6e\x43\x0a\x9b\xe5\x9a\x4e\x92\x64\xd8\x29\xf2\x9e\x0c\x37\x83\xc3\x39\xc7\x60\x66\x88\xe2\x4f\x32\xcd\x66\x9c\x92\xf3\xca\x9a\x0d\xb2\xf4\xa5\xc1\xd2\x45\x3d\xb9\x29\xb9\x41\x05\x35\xae\x06\xa0\x71\x48\xda\xa7\xf0\xa1\xc2\x3f\xad\xbf\x79\x05\xd6\xe8\x39\x58\x43\x30\x45\x5d\x10\x08\x34\x40\x53\x72\x30\x24\x70\xc4\x85\x33\x24\x37\xd2\x1d\x1a\xaf\x91\xad\x4b\xe1\xef\xf5\x28\x40\x7b\xb5\x42\x6d\xac\xae\x02\x2c\x16\xa0\x8c\xa4\x19\x5c\x95\xab\x78\x48\xe0\xcb\x97\x2d\xb8\x1c\x4f\xa1\x53\x0d\x7a\x46\xc7\xac\x82\xa6\xdf\x87\x1f\x03\x61\xcd\x61\x5d\xeb\x26\x0b\xfa\xd0\x0c\xc3\x99\x82\xf2\xd0\x4d\x3a\x77\x49\x27\xb9\xee\x76\x3a\x37\x6f\xbb\x8f\x97\x27\xf5\x58\x2c\xc0\xa1\x19\x13\x7c\xb7\x34\x46\xda\x5f\x6f\x78\x6b\xbb\xed\xd5\x76\x17\x0b\x38\x67\xfb\x80\x4c\x70\x05\xad\x6e\x92\xdc\x24\x9d\xa4\xdb\xe9\x25\x6f\x93\xde\x63\xeb\xe2\xea\x0f\xa3\x66\xdb\x53\x17\x0b\x20\x23\x37\x63\x55\x98\x2d\x55\x7d\x73\x58\xd5\x4d\x0c\x36\xf9\xcb\x8e\x83\x6c\x26\x0d\x64\xe1\x90\xeb\x96\x5b\xfb\x83\x92\x97\x30\x54\x46\x7e\xda\x37\x60\xed\x30\x5f\xd5\x77\x00\xf7\xbd\xb4\x9b\x74\x6e\x3b\x9d\xee\x7d\xe7\xb6\x77\xdf\xbd\x7b\x4c\xdf\xde\xdd\x77\x6f\xaa\x7f\xdb\xa9\x30\xfd\x07\xe5\x48\xb0\x75\x73\x78\x8f\x06\xc7\xe4\xd2\x24\xfc\x3d\x26\xf1\x26\xad\x1d\x55\x2f\xb9\x7d\x4c\xef\xd2\xbb\xb4\x9d\x8e\x9e\xa4\xe9\x8f\xf0\x4f\x6a\x1b\x2b\xe9\xca\x11\xea\xec\xca\xba\xf1\xa5\x30\xfd\x10\xba\x45\x48\x06\xe1\x03\x8a\xd2\xe5\xfd\xa5\x14\x25\x1f\x7e\x96\x74\xf8\xc5\xba\x71\xf3\xf2\xb7\x4b\x85\xba\xdd\xbb\xce\xed\x75\x12\x96\xbf\x4e\xaf\x5f\xa4\xc8\x5d\x4d\x52\xf7\xfe\xfa\xe6\x31\xed\xa5\xbd\x58\x49\x2b\xcf\x5d\xdf\x21\x29\xdc\xbc\xdd\xb3\xf5\x4d\x1a\x69\xe3\xaf\x75\xf2\x92\xfc\xac\x78\x02\x3e\xd7\x8a\xdf\x29\xcf\xd0\x4a\x5b\xab\x69\x3b\xec\xb1\x14\x00\xa0\x64\x3d\xfe\xf7\x23\x3f\xfc\x09\x3e\xb8\x45\xf5\xf6\xa9\x5a\xc8\xad\xa8\x0e\xfc\x0d\x55\xf8\x45\x05\xdf\x7e\xf8\xad\xc6\xb4\xdf\xd3\xe8\xc3\xa7\x83\x61\x7b\xb6\x53\x24\x0c\x7c\x66\xf2\x9d\x4a\xc1\x9b\x2c\xbf\xdc\x54\x06\x11\x85\x01\x1a\x6b\xe6\x99\x2d\x7c\xb0\xc4\xde\xb5\xb0\x9d\xcf\x0b\x83\x05\x4f\x22\x40\xaf\x82\x3f\xc5\xc2\xec\xac\x19\x47\xc2\x25\x42\xa2\x70\x8a\xe7\xe4\x9c\xdd\xbf\x92\xb7\x79\x65\x6c\x7e\x02\x09\xd7\xfb\x49\x28\x04\x3d\x3a\x3a\xc9\xa1\x94\xe1\xce\x9e\x47\xac\x9a\xd9\x29\xc5\xb1\x99\x95\x6a\x34\x7f\x0e\xeb\xe4\x69\xbd\xb5\xf2\x7c\x12\x5a\x3a\xd7\x49\xcc\x1a\xd2\x34\x0d\xce\x1c\x85\x7f\x9e\x58\x4d\xbe\x18\xb2\x23\x8a\x9c\xe2\x68\x44\xce\xa1\x3e\x75\x4e\x13\x54\x46\x99\xf1\x49\xb5\xe2\x5d\x28\x86\x69\x28\x6d\x8f\x82\xf4\x14\x8d\x2a\x73\xbc\x00\x3e\x3a\x69\x33\xa5\x44\x22\xea\x47\x47\x62\xfa\x4d\xca\xcc\xaa\x6c\x5d\x95\x71\x91\x27\x1b\x87\x67\xe8\x99\x5c\xb5\x42\x63\x1f\x61\xf3\x79\x0c\x86\x62\x42\xb1\xdc\xa1\x86\x62\xc7\xb3\x34\x4e\x1b\xb1\x90\xc7\xbf\xd7\x72\x98\x0d\x24\x8d\xca\x86\xa1\xac\x8f\x96\xa9\xbd\x1c\x77\xd6\xf2\x4e\x5e\xaf\xf2\x79\x48\xed\x85\x27\xf7\x9b\xb5\x1c\x7e\x0f\x38\x48\x64\x1c\xa2\xa7\x30\x90\xeb\x62\xac\x8c\x5f\x96\x29\x66\xa4\xc6\x11\x37\x40\x99\xff\x8c\x9e\x37\x29\x5f\xe6\x9e\x48\x0b\x6c\xd8\x83\x36\x3d\x06\x4f\x14\x97\xc5\x62\x44\x6b\xb8\x99\x74\xaa\x75\xcb\x70\x16\x0f\xef\x49\x3f\x18\x37\x47\xc5\xef\xd0\xaf\xaa\xbe\x46\x79\x28\x7c\x81\x5a\xcf\xa1\xdd\x81\x91\x75\xe0\x6d\x16\x5a\x1c\xf4\xd6\x5c\x35\x08\x97\x26\xd2\xe0\x15\x18\x61\xed\x8d\xc8\x58\x53\x57\x33\x22\xec\x1c\x49\x6e\xcb\x8d\xb1\xf0\x61\xf4\xab\xcd\x6b\xac\xcb\x50\xab\xbf\x48\xc6\xdb\x6f\x6f\xce\xe9\xc3\xd9\x9b\x92\x29\xef\x5f\xb2\x4e\xec\x89\xed\xcd\x8d\x38\xbb\x67\xcf\x39\xb6\x56\xc3\x79\x9e\xb5\xe1\x87\x1f\x52\x78\xd3\x98\xfa\xc4\x24\x34\x08\xda\x8e\xbf\x5d\xfe\x5b\x97\xcd\x8e\x72\xad\xc4\xaa\x1f\x5d\x49\x5f\x93\x5b\x8f\x6b\x76\xf8\x3f\x12\xfc\x6f\x8d\xde\xf7\x8d\x97\xbe\x57\x9b\x8c\x63\x47\x94\x91\xe1\xf2\xdd\xcd\x0b\x1b\x74\xac\x8a\x99\x03\xd9\x76\xbb\x3b\x79\x05\x3c\x51\x3e\x78\x2d\x4f\x08\x42\xa6\x07\x9e\x20\x87\x81\xd5\x12\x24\x01\x85\xb3\xde\x2f\x51\xb6\xb9\xb6\xe3\x79\x0a\x52\xf4\xab\x46\x2d\xf4\x96\xc2\x66\xa5\xb7\x57\x43\x2b\xc7\xf1\x0f\xbf\xf7\x7e\x5b\x0a\x0a\x46\x5a\x6b\xbd\xba\x54\x76\xa1\xff\x58\x5f\x83\x26\xd6\xf3\xa1\x2b\xa2\x6e\x82\x77\xe8\xf9\x8f\x5c\x22\xd3\x4f\x66\xef\xaa\xde\x7e\x16\x98\x90\xce\x53\x28\x42\x37\xc4\x2a\x23\xcf\x98\xe5\x60\x47\xa5\xea\x1a\x3d\x43\x51\xca\x01\x4f\x64\x00\x8d\x04\xcc\x73\xad\x48\x96\x8a\x95\x66\x09\x1b\xaa\x05\xf1\xd1\x57\xa0\xfd\x16\x6d\xfd\xdc\x13\xdf\xa8\xbd\x50\xcb\x7f\x64\x57\xbf\x33\xba\xc6\xc4\xf9\xcf\xed\x8b\x8b\xc6\x3a\xaa\xf6\x08\xc4\x34\xe3\x03\x0f\x40\x69\xb3\x6c\xf8\x29\x14\xe4\x70\x9e\x5c\x40\xe5\xc4\x80\xe2\xa9\x50\x8e\x24\xf8\x42\x08\xf2\x7e\x54\x68\x3d\x4f\xe1\x67\x23\x5c\x19\xa9\xa8\xd7\xae\x16\x00\x92\x5f\xf1\xb4\x18\x36\x5d\xda\xee\xa9\xb0\x4c\x95\xe9\x8e\x3c\x2a\x2e\x16\xe0\x68\x4c\xb3\xb0\x4f\x14\xf4\xa3\xd6\xd0\x5a\xee\xfe\xe3\xc7\xf3\xf3\x0f\x49\xfb\xfe\xd3\xbf\x2e\x3e\x7e\xbc\x80\xab\x37\xad\x95\xa4\xd6\x77\x8b\xce\x97\xd6\x61\x3b\x3b\xc2\x1c\x05\xab\xe9\x91\xe4\x7c\x22\xd7\xbd\xae\xff\x0f\xc2\x56\x8e\x63\x9b\x5f\x6c\x92\x59\xc8\xa6\xa7\xeb\xc6\x10\xc1\x85\xdf\x7b\x19\xdc\xbe\xc9\x46\xca\x79\x5e\x66\x75\x53\x64\x43\x72\x27\x7a\x52\x7c\x0e\xad\x72\x7c\xb0\x19\x2a\xf3\x2e\xf4\x9e\x47\xde\xab\x97\xd9\x7d\xb0\xcd\x36\x5b\x2f\xb7\x5e\xcd\x06\x4a\xfa\x9d\x5b\xe8\xd7\x30\x0e\x3f\x3f\x94\xf7\xcb\x83\xf2\x95\x79\x24\xfc\x52\x64\xe4\x94\x80\x1f\xbd\x57\x63\x13\x3c\x0e\x7e\x2d\x6f\xa2\xa3\x35\xf9\xb3\x2c\x2d\x0d\xbe\xc7\xd9\x7f\x4b\x9f\x3a\xac\xe4\x7a\xc7\x83\x0c\x67\x83\xd2\x91\x1a\x2b\x4b\xfc\x85\x66\x1c\x27\xd0\xd0\x8c\x1b\x24\xfe\x3f\x00\x00\xff\xff\x22\xea\x81\xd5\xba\x1a\x00\x00"),
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x59\x7b\x8f\xdb\xb8\x11\xff\x7f\x3f\xc5\x60\x53\x9c\xd7\xe9\xee\xd6\xde\xf7\xaa\x58\x14\x69\xf7\x80\x06\xb8\x04\xc1\x1d\xda\x3f\xf2\x80\x40\x93\x63\x89\x17\x6a\xa8\x90\xd4\xae\x7d\x69\xbe\x7b\x31\x92\x2c\xc9\x6f\x39\x0d\x0a\x2f\x2c\x9b\xfe\x71\x38\xf3\xe3\x70\x1e\xdc\x33\x20\x91\x61\x04\x99\x25\x1d\xac\x3b\x02\xf0\x28\x9c\x4c\x23\x18\x48\x7a\x78\x53\x8d\x0e\x8e\x00\xa6\xda\x04\x74\x11\x0c\x4e\x24\x3d\xd4\xe8\x21\xff\x20\x42\x70\x7a\x52\x04\xf4\xd1\x11\x00\x40\x86\xc1\x69\x59\x7f\x01\x08\xa9\x43\xa1\x9a\xaf\x00\x61\x9e\x63\x04\x89\x28\x12\xac\xc7\x64\xe1\x1c\x52\x90\x96\x08\x65\xd0\x96\x7e\x20\x3a\xd8\x20\xcc\x77\x4b\x16\x21\x13\xb3\x1e\x16\xb4\xa8\x1c\x1d\xcf\x4f\x75\xd8\x39\x41\x05\x31\x31\xe8\xf5\x1f\xb8\x0b\xc5\xeb\x3e\x0b\x1d\xd0\xed\x14\x66\x73\xaf\x49\x07\x2d\x02\xaa\x55\x9c\xb4\x05\x05\x74\x2d\x52\xda\x2c\x37\xb8\x17\x89\x14\x9c\x46\xef\x91\xc2\x6e\xe0\x64\x1e\xfa\xc0\x68\x22\xe4\x67\xa4\xdd\x34\x3e\xa1\xf3\xda\x52\x0b\xa9\x7c\x29\xae\x5c\xd4\xa3\x7b\x42\x17\xd7\xa0\x06\x53\x89\xd1\x34\xb5\xcd\xd0\x93\x30\x05\xc6\x46\x4c\xd0\x44\xb0\x8a\x4f\xd1\xe4\xcd\x28\xd8\x29\x84\x14\xe1\xf2\xee\xbe\x96\x7f\x5e\x03\x7d\x10\x2e\x04\xcd\x0b\xff\xc4\x8f\x58\x5a\x5a\x15\xb5\x6e\x01\xc0\x0b\xc0\x99\x60\x8a\x41\x7b\xb8\x18\x8d\xef\x46\xe3\xd1\xe5\xc5\x78\x7c\x73\x75\xf1\xfe\xb4\x41\x55\x1a\x4e\xad\xcb\x44\x88\x20\x41\x42\x27\x8c\xfe\x03\x55\x1c\x74\xb6\xe2\x8f\x3c\x12\xc1\xcb\xcd\x4a\xb4\xce\xda\xb2\x76\xb6\x51\xb1\x15\x36\xdb\x89\xb1\x2a\x9c\x08\x5d\xbb\x00\x4a\xee\x7c\x04\x1f\xb4\x3a\x85\x89\x26\xf5\xa9\xf3\x63\x63\x62\x67\x33\xd9\xf0\x8e\x2e\x70\x7f\x1d\x5d\x8c\xc6\xb7\xe3\xf1\xc5\xfd\xf8\xf6\xfa\xfe\xe2\xee\x7d\x74\x75\x77\x7f\x71\x53\xbf\x9f\x45\x92\x1e\x1e\xb5\x43\x19\xac\x9b\xc3\x1b\x41\x22\x41\x17\x8d\xf8\xb5\x5d\xe6\x4d\xd4\x21\xf4\x7a\x74\xfb\x3e\xba\x8b\xee\xa2\xb3\x68\xfa\x45\xd1\xc3\x54\x7c\xc6\x33\xb2\x0a\xcf\x1d\x0a\x93\x9d\x5b\x97\x9c\x4a\x7a\x60\x7f\x2f\xf8\x04\xf1\x17\x21\x4b\xaf\xf4\xa7\x4a\x96\x78\x7e\x96\x68\xfe\x60\x5d\xb2\x4f\x81\x5b\x56\xe0\x76\x7c\x71\x71\x37\xbe\xbd\x1c\xb1\x02\x97\xd1\xe5\x77\x1a\x73\xd7\x91\x75\x71\x7f\x79\xf3\x3e\xba\x8e\xae\xfb\xcb\x5a\x78\x59\x13\x7e\x23\xb8\xb9\x5a\xe3\xfc\x26\xea\xcd\x35\xce\x82\x13\xb2\x73\x8c\xf9\xcf\xe7\x46\x87\x08\x8e\xa3\xe3\xa5\xe1\xd2\x7d\x23\x18\xaf\x0f\xee\xf3\xe9\x25\xf7\x5a\x1a\x03\xd0\x2a\x82\xae\x46\xfc\x62\xdf\x8b\xe0\x7a\xcd\xb7\x53\xed\x83\x4d\x9c\xc8\x7a\xf8\xb7\x48\x30\xf6\x28\x2d\x29\xdf\x41\x57\x71\x40\x24\xb8\x88\x01\xf5\x71\x33\x73\xb0\x39\x52\x47\x80\x3f\x05\x87\x46\x04\xfd\x84\x10\x6c\x19\x2f\xaa\x58\x31\xf0\x20\x8d\x95\x9f\x17\x31\x83\x5f\x22\xc1\x08\x82\x2b\xba\x06\x4f\x0a\xf9\x19\x03\x1f\xa7\x9b\xd1\x29\x5c\x8e\x46\xa7\x70\xcf\x6f\x97\x37\xfc\x3e\xbe\xba\xe2\xc7\xdd\x4d\xf9\xb8\x19\x5d\xdd\x8d\x46\x9f\xfe\xaf\x1b\xa3\xd0\xe9\xa7\x45\x46\xb0\xb9\x8f\x35\xc5\x53\xa3\x93\xb4\x59\x74\x3d\xa0\x54\xf4\xd9\x1c\xab\xd8\xe1\xa1\x49\x41\x30\x29\x02\x90\x0d\x30\xc7\x00\x4d\xba\x59\x70\x24\xd1\x44\x47\x2b\xea\x0e\x34\x85\x93\xb6\x8e\x38\xef\x26\xb4\x0f\xa3\x4f\x43\x38\x83\x75\x44\x23\x99\x11\x83\xa3\xa3\x95\x4a\x26\xf6\x19\xe5\x2b\xe5\x8c\xa7\x2c\x3f\x6d\xcb\x97\x1e\xd5\x8b\x20\x4b\xf3\xcc\x16\x9e\xfd\xd0\x47\x2b\x91\x7f\x39\xc5\x15\x24\x8a\x90\xf6\x00\x7a\xcd\x9a\xf7\x05\x07\x67\x29\xe9\x09\x2e\x21\x28\x0b\xa7\xc3\x1c\x9d\xb3\x6e\x0f\x5e\x93\xcd\xf7\x40\xb8\x06\xd9\x0b\xe2\xbd\x10\x0e\xf7\xe2\x84\x52\x5c\x58\xcc\xf7\x02\x1d\x66\xf6\x09\xfb\x61\x33\xab\xf4\x74\x7e\x08\xd6\xa9\xfd\x76\x1b\x0e\x30\xfb\x40\x55\xad\xbc\x17\x66\x09\x0d\x3e\xa1\xe9\x09\x7f\x4e\xad\x41\x5f\x4c\x82\x43\xec\x39\xc5\xe1\x14\x9d\x13\x66\x0f\x4c\xa6\x42\x93\xa6\x64\x0f\xec\x10\x17\xea\x83\xe9\x84\xd2\xde\x40\xfc\xd2\x1b\xaa\x69\x7b\x95\xbe\x75\x52\x3b\xa5\x84\xec\x9e\x57\x16\xb9\x0e\xe5\xd3\x0f\xa9\x85\xeb\xda\xda\x61\x28\x1c\xa1\xea\xb9\xb3\xfd\xe0\x99\xf0\x01\x5d\xbd\xc2\x2a\xb4\x1b\xbf\xa5\xcd\xe7\x7d\x60\x42\xa6\xd8\x17\xb7\xa9\xeb\x59\x56\xcf\x1b\xf1\xb4\x13\xd6\xc6\x71\xa3\x26\x59\xec\xac\x0d\x2b\x41\xbc\x0e\xde\x1c\xc7\x0b\x8f\xee\x57\x6b\x03\x7f\x66\x38\x28\x11\xc4\x44\x78\xe4\x81\xdc\x14\x89\x26\xcf\x1f\xa5\xa5\xa9\x4e\x38\xdc\xbf\x28\xb3\x77\x2d\x02\xd8\xae\x39\xd7\xe9\x02\x64\xaa\x8d\x5a\x54\x02\x75\xaf\x32\xf0\x15\xe2\x14\x9e\x53\x2d\x53\xf8\x4c\xf6\xd9\x97\x02\x7c\x31\x9d\xea\x19\xa7\xb4\xdf\xad\xae\x2b\xef\xae\x8e\xfd\x94\xe9\x36\xd5\x76\xf2\x3b\xca\xf0\x0f\x23\xbc\x7f\x20\xff\xf7\x4a\x81\xd7\xe4\x83\x20\x89\x65\x9f\x0d\xe0\xa5\x65\xaa\xbc\xa6\xc4\x54\xbc\x67\x22\xf0\x92\xb9\xe0\x1d\x8f\x15\x1d\xad\x17\x57\xe4\xbd\x11\xb9\x3a\xab\x54\x8e\xa0\x7a\x96\x54\x58\x32\xf3\xd2\x9c\x36\x09\x82\x70\x08\x3e\x15\x0e\x15\x3c\xeb\x90\x02\x5b\x12\xcb\x54\x50\x82\xc6\x26\x7f\x2d\xe1\x6c\x33\x08\x52\x8b\xba\x61\x91\x34\xcb\xc9\x4b\xfc\x9d\x2f\x67\x58\xf8\xa9\x14\xd7\x8e\x6c\x4a\xb9\x65\xc2\x21\x33\x5f\xf5\x90\xae\xb7\x95\xdb\xd2\xd3\xe5\x5a\xec\x46\x27\xde\x06\x4e\x75\x28\x2b\x9b\x5d\x6a\xd4\x15\x63\x3b\x69\x5f\x43\x9f\x89\x59\x7f\xf0\x9a\xf4\x8d\x81\x6a\xab\xf8\x15\xf4\x8b\xaa\x0c\x64\x6f\x2f\x7c\x21\x8c\x99\xc3\xd9\x18\xa6\xd6\x81\xb7\x19\x82\x43\xe1\x2d\x9d\xef\x10\xae\xa8\x27\xe1\x8a\xfa\xb2\xad\xe8\x50\xaa\x15\xf5\xa2\x2e\x13\x33\x45\x87\x90\xac\x68\x13\x67\xbb\x04\xff\x68\x7a\x89\xcb\xf3\xf2\x0a\xa0\x3f\x7f\x6b\x73\xf6\x6f\xce\xda\x94\x4c\x7b\xff\x3d\xeb\xf4\xdd\xb1\xb5\xb9\x3d\xf6\xee\xe0\x39\xdb\xd6\xda\xb9\x9f\x4b\x5d\x4f\x7b\x6e\xe2\xa9\x36\x26\x5e\x32\x6f\x7d\xc1\xaa\xfb\x99\x72\xc3\xdc\xb9\x45\x2a\x85\x40\xa9\xf1\xc0\x43\x26\x66\x3a\x2b\x32\x60\x83\x41\x13\x14\x1e\x77\x36\x41\xca\x16\x13\x83\xdd\x2e\xa7\x36\xab\xd5\x8d\x45\x71\xbb\x03\x7f\x81\x75\xf4\x5a\x70\x59\x69\x8c\x96\x43\xf9\x8f\xcc\xaa\xad\x12\x11\xbc\x5c\x0d\xf1\xcd\xfa\x0e\x73\xa3\xe5\xe2\xa6\x69\xb1\x78\x23\xe8\x68\x7b\x3a\x54\xfe\xba\x33\x59\x24\x0e\x31\x43\x0a\x65\x5a\x5c\x24\xc5\xaa\x5c\x5e\x56\x66\x43\x3a\xe4\x02\x40\x7b\x3e\xa6\x9c\xa8\xb8\xbc\x80\x90\x8a\xc0\x03\x8b\x25\x50\x81\x90\xce\x7a\x86\x30\xce\xe6\xc6\x26\xf3\x08\x94\x7c\xa8\x2f\x5d\xf8\xb6\x48\xda\xac\x3c\xde\xf5\x50\x2d\x9e\xfc\xe3\x6f\xd7\xbf\x56\x82\x98\xc3\xc6\xea\x45\x25\xb3\x0a\xfa\xa7\xf5\x1d\x50\x6a\x7d\xd8\x94\x13\xbb\x14\xfc\x22\x7c\xf8\x57\xae\x44\xc0\x9f\x49\x45\x6b\x17\x23\x5d\x2f\x6d\x3d\xb5\x20\x3d\x83\xa0\x33\xf4\x41\x64\xf9\xc2\x5f\x8d\xf0\x01\x8a\x52\x16\x78\xc4\x2a\xa9\x8b\x3c\x37\x1a\x55\x69\x5c\x49\x0d\x2b\xd5\x46\xae\x1e\xd7\x09\x07\xdc\x42\xd6\x36\xb1\x4f\xc4\x5b\x8d\xdc\x79\x81\x53\x8f\x83\xd7\x24\xf1\x40\xab\xfe\x97\x7b\x9d\xcd\xda\xfe\xc6\xb7\xc6\xed\xa6\x6c\x62\xa0\x17\x7d\x5b\xa5\x87\xc2\x47\x7b\x29\xae\x5d\x1e\x3e\x04\x9c\x85\x8d\x37\xb7\xd1\x76\xfd\x79\x05\xf8\x99\xbb\x3a\x38\x19\x0d\xa1\xf6\x53\x10\xf2\x4b\xa1\xb9\x2c\xf4\x85\x94\xe8\xfd\xb4\x30\x66\x1e\xc1\x6b\x92\xae\x3c\x8c\xc2\x34\x9c\x33\x00\x15\xaa\xce\xc2\xc1\x09\xf2\x46\x04\xeb\x22\xf8\x4f\x67\x1c\xe0\xeb\x57\xc0\x4c\x07\x38\x71\x98\xe0\x8c\x57\x13\x12\x5f\x19\x03\xc7\x27\x7f\xf3\xc3\x4a\x8f\x8f\x1f\x4f\x4e\x3e\x8c\xce\xee\x3f\xfd\x79\xf8\xf1\xe3\x10\xce\x5f\x1e\xc3\x79\x49\x23\x1c\xff\xe9\xeb\xf8\xdb\xf1\x10\x4e\x94\x96\x01\x8e\xd9\xde\xc5\x6f\x43\xf8\xf6\x6d\x2f\x53\x07\x3a\x63\xb5\x01\x71\x4d\x41\x47\x4e\xe5\x8b\x63\xd0\x9d\x83\xd5\x11\xb7\x46\xcd\x29\x8c\xc0\x86\x14\xdd\xb3\x6e\xb3\x42\x4f\xa2\x52\xe1\xdf\x39\x9c\xea\x19\x1c\xb7\xdb\xb4\xc1\xea\xae\x09\x0e\x45\xce\xb9\xea\x69\x6b\x1a\x7d\x01\x4d\x5c\xf5\x60\xf8\xaa\x73\x82\x84\x22\xa4\xa5\x41\x92\x1e\x6a\x49\x75\xd3\x54\x47\x90\xaa\x9b\xe0\x8f\xf3\x86\x3e\xdc\xde\x17\x65\x22\xcf\x35\x25\xc0\xa1\xba\x6f\x2f\xd4\x1a\x31\x1c\xf4\x6f\x7a\xda\x08\xfb\x5a\x35\x3b\x1b\x6b\xb5\x2f\x1d\x0d\xba\xff\xeb\x5c\xd2\x24\xd8\x7c\xd8\xe6\x1b\xee\x32\x37\x24\x9b\x95\xb8\xcd\x7e\x50\x78\x5a\xe5\x7c\xb9\xba\x9a\x6a\xe7\x43\xd5\x62\x51\x91\x4d\xd0\xed\x86\x1b\x71\x08\x5a\xe7\xe2\xd1\x66\x42\xd3\x2f\x7c\x01\x15\x1d\x6d\x74\xfc\x6a\x1b\xe2\x65\xec\x46\x3f\x69\xd8\xcb\xad\xd7\xb3\x58\x2b\xdf\xe5\x4e\xd2\xc3\x3b\x1e\x87\xd7\x8f\x65\xdf\xfd\xa8\x7d\x4d\x8f\x82\xb7\x45\x86\x4e\x4b\x78\xe5\xbd\x4e\x88\x23\x06\xbc\x2b\x6b\x89\xad\x55\xc5\x41\x4c\x2b\x12\x6f\xc4\xec\xdf\x7c\x04\xb6\x18\xd9\x68\x1c\x67\x62\x16\x97\xe1\x63\xa3\x89\x8d\xbc\xb7\x38\x0b\xfd\x04\x12\xce\xc2\x4e\x89\x4b\xa5\x66\x3b\x6f\xea\xb0\x11\xbe\x45\x34\x43\x8e\x36\x89\x6c\xa3\x8e\x56\x5c\xbb\xf0\x1e\xf3\xd1\xd2\xc4\xa7\x11\x1e\xdf\xbe\x02\xc7\x4e\x02\x13\x9c\x5a\x87\xa0\x03\x5f\x67\xcc\x52\x51\xf8\x03\x6f\xe3\x3b\xd4\x6e\xbe\x8c\xef\x72\xf5\x61\xf4\x69\x38\x38\xfa\xef\x00\xda\x1a\x5c\xbf\x56\x20\x00\x00"),
 		},
 	}
 	fs["/"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/ldap.v2"
+)
+
+// bindConfig runs a source as its own identity rather than -ldap.bind's, over a separate
+// connection; for example cn=config under Directory Manager while everything else uses a
+// low privilege account.  Sources with the same dn and password_file share a connection,
+// so a yaml anchor makes for a named credential group.
+type bindConfig struct {
+	DN           string `yaml:"dn"`
+	PasswordFile string `yaml:"password_file"`
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (b *bindConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain bindConfig
+
+	if err := unmarshal((*plain)(b)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(b.X, "bind"); err != nil {
+		return err
+	}
+
+	if b.DN == "" {
+		return fmt.Errorf("bind dn is either empty or undefined")
+	}
+	if _, err := ldap.ParseDN(b.DN); err != nil {
+		return fmt.Errorf("bind dn is malformed: %s", err)
+	}
+	if b.PasswordFile == "" {
+		return fmt.Errorf("bind password_file is either empty or undefined")
+	}
+	// read it now so a missing file fails at load rather than at each scrape.
+	if _, err := b.identity().password(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (b *bindConfig) identity() *bindIdentity {
+	return &bindIdentity{dn: b.DN, passwordFile: b.PasswordFile}
+}
+
+// bindIdentity is comparable so the exporter can key connections by it.
+type bindIdentity struct {
+	dn           string
+	passwordFile string
+}
+
+// password is reread at each bind so rotating the file doesn't require a restart.
+func (b bindIdentity) password() (string, error) {
+	content, err := ioutil.ReadFile(b.passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed reading the password of bind dn %s: %s", b.dn, err)
+	}
+	password := strings.TrimRight(string(content), "\r\n")
+	if password == "" {
+		// an empty password is an unauthenticated bind, which silently succeeds.
+		return "", fmt.Errorf("password_file %s of bind dn %s is empty", b.passwordFile, b.dn)
+	}
+	return password, nil
+}
+
+func (b bindIdentity) String() string {
+	return b.dn
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"github.com/google/cel-go/interpreter/functions"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// celConfig is an alternative to translator templates using Common Expression Language
+// expressions.  The expressions are evaluated for each value of the attribute; value is the
+// value being translated, values is every value of the attribute, attributes maps the entry's
+// requested attributes to their values, dn is the entry's DN, and now is the scrape's reference
+// time.  Attributes the expressions reference are added to the search, and entries lacking one
+// are skipped unless it's only tested via has().  generalizedTime(string) and filetime(string)
+// convert LDAP timestamps to CEL timestamps.
+type celConfig struct {
+	// if unset, the value is always 1.
+	Value string `yaml:"value"`
+	// label name -> expression; expressions must result in strings.
+	Labels map[string]string `yaml:"labels"`
+
+	translator *celTranslator
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (c *celConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain celConfig
+
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.X, "cel"); err != nil {
+		return err
+	}
+
+	for label := range c.Labels {
+		if label == "" || len(strings.TrimSpace(label)) != len(label) {
+			return fmt.Errorf("cel label cannot have whitespace and must be nonempty: '%s'", label)
+		}
+	}
+	return nil
+}
+
+// compile type checks the expressions against env, which differs for attribute and derived metrics.
+func (c *celConfig) compile(env *cel.Env) error {
+	t := &celTranslator{labels: make(map[string]cel.Program)}
+	var asts []*cel.Ast
+	if c.Value != "" {
+		prg, ast, err := compileCEL(env, c.Value, "value", types.DoubleType, types.IntType, types.UintType, types.BoolType, types.TimestampType, types.DurationType)
+		if err != nil {
+			return err
+		}
+		t.value = prg
+		asts = append(asts, ast)
+	}
+	for label, expression := range c.Labels {
+		prg, ast, err := compileCEL(env, expression, "label "+label, types.StringType)
+		if err != nil {
+			return err
+		}
+		t.labels[label] = prg
+		asts = append(asts, ast)
+	}
+	t.required, t.optional = celAttributeReferences(asts...)
+	c.translator = t
+	return nil
+}
+
+func (c *celConfig) labelNames() []string {
+	var names []string
+	for label := range c.Labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// celEnv is for attribute metrics, celDerivedEnv is for derived metrics which have no single value.
+var (
+	celEnv = newCELEnv(
+		decls.NewIdent("value", decls.String, nil),
+		decls.NewIdent("values", decls.NewListType(decls.String), nil),
+	)
+	celDerivedEnv = newCELEnv()
+)
+
+func newCELEnv(extra ...*exprpb.Decl) *cel.Env {
+	env, err := cel.NewEnv(
+		ext.Strings(),
+		cel.Declarations(append([]*exprpb.Decl{
+			decls.NewIdent("attributes", decls.NewMapType(decls.String, decls.NewListType(decls.String)), nil),
+			decls.NewIdent("dn", decls.String, nil),
+			decls.NewIdent("now", decls.Timestamp, nil),
+			decls.NewFunction("generalizedTime",
+				decls.NewOverload("generalizedTime_string", []*exprpb.Type{decls.String}, decls.Timestamp)),
+			decls.NewFunction("filetime",
+				decls.NewOverload("filetime_string", []*exprpb.Type{decls.String}, decls.Timestamp)),
+		}, extra...)...),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed building the CEL environment: %s", err))
+	}
+	return env
+}
+
+var celFunctions = cel.Functions(
+	&functions.Overload{Operator: "generalizedTime_string", Unary: celTimestampFunc(parseGeneralizedTime)},
+	&functions.Overload{Operator: "filetime_string", Unary: celTimestampFunc(parseFiletime)},
+)
+
+func celTimestampFunc(parse func(string) (time.Time, error)) func(ref.Val) ref.Val {
+	return func(value ref.Val) ref.Val {
+		s, ok := value.(types.String)
+		if !ok {
+			return types.ValOrErr(value, "no such overload")
+		}
+		t, err := parse(string(s))
+		if err != nil {
+			return types.NewErr("%s", err)
+		}
+		ts, err := ptypes.TimestampProto(t)
+		if err != nil {
+			return types.NewErr("%s", err)
+		}
+		return types.Timestamp{Timestamp: ts}
+	}
+}
+
+// compileCEL parses and type checks an expression, requiring it result in one of the allowed types.
+func compileCEL(env *cel.Env, expression string, what string, allowed ...ref.Type) (cel.Program, *cel.Ast, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, nil, fmt.Errorf("cel %s expression %q is invalid: %s", what, expression, issues.Err())
+	}
+	if result := ast.ResultType(); result.GetDyn() == nil {
+		ok := false
+		for _, t := range allowed {
+			if celTypeName(result) == t.TypeName() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			var names []string
+			for _, t := range allowed {
+				names = append(names, t.TypeName())
+			}
+			return nil, nil, fmt.Errorf("cel %s expression %q results in %s; it must be one of %s", what, expression, celTypeName(result), strings.Join(names, ", "))
+		}
+	}
+	prg, err := env.Program(ast, celFunctions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cel %s expression %q is invalid: %s", what, expression, err)
+	}
+	return prg, ast, nil
+}
+
+// celAttributeReferences finds the attributes.name and attributes["name"] references in the
+// expressions.  References only tested via has() are optional.
+func celAttributeReferences(asts ...*cel.Ast) (required []string, optional []string) {
+	seen := make(map[string]bool)
+	var walk func(e *exprpb.Expr)
+	walk = func(e *exprpb.Expr) {
+		if e == nil {
+			return
+		}
+		switch {
+		case e.GetSelectExpr() != nil:
+			sel := e.GetSelectExpr()
+			if sel.GetOperand().GetIdentExpr().GetName() == "attributes" {
+				seen[sel.GetField()] = seen[sel.GetField()] || !sel.GetTestOnly()
+			}
+			walk(sel.GetOperand())
+		case e.GetCallExpr() != nil:
+			call := e.GetCallExpr()
+			if call.GetFunction() == "_[_]" && len(call.GetArgs()) == 2 && call.GetArgs()[0].GetIdentExpr().GetName() == "attributes" {
+				if c := call.GetArgs()[1].GetConstExpr(); c != nil && c.GetConstantKind() != nil {
+					if name, ok := c.GetConstantKind().(*exprpb.Constant_StringValue); ok {
+						seen[name.StringValue] = true
+					}
+				}
+			}
+			walk(call.GetTarget())
+			for _, arg := range call.GetArgs() {
+				walk(arg)
+			}
+		case e.GetListExpr() != nil:
+			for _, elem := range e.GetListExpr().GetElements() {
+				walk(elem)
+			}
+		case e.GetStructExpr() != nil:
+			for _, entry := range e.GetStructExpr().GetEntries() {
+				walk(entry.GetMapKey())
+				walk(entry.GetValue())
+			}
+		case e.GetComprehensionExpr() != nil:
+			comp := e.GetComprehensionExpr()
+			walk(comp.GetIterRange())
+			walk(comp.GetAccuInit())
+			walk(comp.GetLoopCondition())
+			walk(comp.GetLoopStep())
+			walk(comp.GetResult())
+		}
+	}
+	for _, ast := range asts {
+		walk(ast.Expr())
+	}
+	for name, is_required := range seen {
+		if is_required {
+			required = append(required, name)
+		} else {
+			optional = append(optional, name)
+		}
+	}
+	sort.Strings(required)
+	sort.Strings(optional)
+	return required, optional
+}
+
+func celTypeName(t *exprpb.Type) string {
+	switch t.GetPrimitive() {
+	case exprpb.Type_BOOL:
+		return types.BoolType.TypeName()
+	case exprpb.Type_INT64:
+		return types.IntType.TypeName()
+	case exprpb.Type_UINT64:
+		return types.UintType.TypeName()
+	case exprpb.Type_DOUBLE:
+		return types.DoubleType.TypeName()
+	case exprpb.Type_STRING:
+		return types.StringType.TypeName()
+	case exprpb.Type_BYTES:
+		return types.BytesType.TypeName()
+	}
+	switch t.GetWellKnown() {
+	case exprpb.Type_TIMESTAMP:
+		return types.TimestampType.TypeName()
+	case exprpb.Type_DURATION:
+		return types.DurationType.TypeName()
+	}
+	return t.String()
+}
+
+type celTranslator struct {
+	value  cel.Program
+	labels map[string]cel.Program
+	// the other attributes of the entry the expressions reference; required ones must be
+	// present for the expressions to be evaluated.
+	required []string
+	optional []string
+}
+
+// attributes returns every attribute the expressions reference, so the source can search for them.
+func (c *celTranslator) attributes() []string {
+	return append(append([]string{}, c.required...), c.optional...)
+}
+
+// celVariables returns the variables common to attribute and derived expressions.
+func celVariables(ctx *scrapeContext) (map[string]interface{}, error) {
+	attributes := make(map[string][]string)
+	dn := ""
+	if ctx.entry != nil {
+		dn = ctx.entry.DN
+		for _, attribute := range ctx.entry.Attributes {
+			attributes[attribute.Name] = attribute.Values
+		}
+	}
+	now, err := ptypes.TimestampProto(ctx.now)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"attributes": attributes,
+		"dn":         dn,
+		"now":        now,
+	}, nil
+}
+
+func (c *celTranslator) translate(ctx *scrapeContext, values []string) ([]*translationResult, error) {
+	if ctx.entry != nil {
+		for _, attribute := range c.required {
+			// like derived metrics, entries lacking a referenced attribute are skipped rather
+			// than failing the scrape.
+			if len(ctx.entry.GetAttributeValues(attribute)) == 0 {
+				return nil, nil
+			}
+		}
+	}
+	vars, err := celVariables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vars["values"] = values
+	var results []*translationResult
+	for _, value := range values {
+		vars["value"] = value
+		result, err := c.evaluate(vars)
+		if err != nil {
+			return nil, fmt.Errorf("value %q: %s", value, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (c *celTranslator) evaluate(vars map[string]interface{}) (*translationResult, error) {
+	result := &translationResult{Value: 1, Labels: make(map[string]string, len(c.labels))}
+	if c.value != nil {
+		out, _, err := c.value.Eval(vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed evaluating cel value expression: %s", err)
+		}
+		if result.Value, err = celNumber(out); err != nil {
+			return nil, fmt.Errorf("failed evaluating cel value expression: %s", err)
+		}
+	}
+	for label, prg := range c.labels {
+		out, _, err := prg.Eval(vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed evaluating cel expression for label %s: %s", label, err)
+		}
+		s, ok := out.(types.String)
+		if !ok {
+			return nil, fmt.Errorf("cel expression for label %s resulted in %s rather than a string", label, out.Type().TypeName())
+		}
+		result.Labels[label] = string(s)
+	}
+	return result, nil
+}
+
+// celNumber converts an expression result into a sample value; timestamps are unix seconds,
+// durations are seconds.
+func celNumber(out ref.Val) (float64, error) {
+	switch v := out.(type) {
+	case types.Double:
+		return float64(v), nil
+	case types.Int:
+		return float64(v), nil
+	case types.Uint:
+		return float64(v), nil
+	case types.Bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case types.Timestamp:
+		t, err := ptypes.Timestamp(v.Timestamp)
+		if err != nil {
+			return 0, err
+		}
+		return unixSeconds(t), nil
+	case types.Duration:
+		d, err := ptypes.Duration(v.Duration)
+		if err != nil {
+			return 0, err
+		}
+		return d.Seconds(), nil
+	}
+	return 0, fmt.Errorf("result %v of type %s isn't numeric", out, out.Type().TypeName())
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/ldap.v2"
+	"gopkg.in/yaml.v2"
+)
+
+func TestCELAttributeReferences(t *testing.T) {
+	for _, tc := range []struct {
+		expression string
+		required   []string
+		optional   []string
+	}{
+		{`double(value)`, nil, nil},
+		{`double(value) * double(attributes.bar[0])`, []string{"bar"}, nil},
+		{`double(attributes["a-b"][0]) + double(size(attributes.c))`, []string{"a-b", "c"}, nil},
+		{`has(attributes.bar) ? double(attributes.bar[0]) : 0.0`, []string{"bar"}, nil},
+		{`has(attributes.bar) ? 1.0 : 0.0`, nil, []string{"bar"}},
+	} {
+		var config celConfig
+		if err := yaml.Unmarshal([]byte("value: '"+tc.expression+"'"), &config); err != nil {
+			t.Errorf("cel %s failed: %s", tc.expression, err)
+		} else if err := config.compile(celEnv); err != nil {
+			t.Errorf("cel %s failed: %s", tc.expression, err)
+		} else if !reflect.DeepEqual(config.translator.required, tc.required) || !reflect.DeepEqual(config.translator.optional, tc.optional) {
+			t.Errorf("cel %s references %v and optionally %v, expected %v and %v", tc.expression, config.translator.required, config.translator.optional, tc.required, tc.optional)
+		}
+	}
+}
+
+func TestCELOtherAttributes(t *testing.T) {
+	sources, err := LoadConfig(`
+- name: ratio
+  search: cn=monitor
+  scope: base
+  attributes:
+    metrics:
+      foo:
+        type: gauge
+        help: foo scaled by bar.
+        cel:
+          value: 'double(value) * double(attributes.bar[0])'
+`)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %s", err)
+	}
+	source := sources[0]
+	if !containsString(source.SearchRequest.Attributes, "bar") {
+		t.Errorf("attributes referenced by cel expressions should be searched for, got %v", source.SearchRequest.Attributes)
+	}
+
+	ctx := &scrapeContext{now: time.Now(), entry: ldap.NewEntry("cn=monitor", map[string][]string{"foo": {"3"}, "bar": {"2"}})}
+	results, err := source.MetricAttributes["foo"][0].(*GaugeMetricAttribute).translator.translate(ctx, []string{"3"})
+	if err != nil {
+		t.Errorf("translating foo failed: %s", err)
+	} else if len(results) != 1 || results[0].Value != 6 {
+		t.Errorf("translating foo = %+v, expected a value of 6", results)
+	}
+
+	for _, tc := range []struct {
+		entry    *ldap.Entry
+		expected int
+	}{
+		{ldap.NewEntry("cn=monitor", map[string][]string{"foo": {"3"}, "bar": {"2"}}), 1},
+		// entries lacking a referenced attribute are skipped rather than failing the scrape.
+		{ldap.NewEntry("cn=monitor", map[string][]string{"foo": {"3"}}), 0},
+	} {
+		ch := make(chan prometheus.Metric, 10)
+		if err := source.scrapeMetrics(&scrapeContext{now: time.Now()}, &ldap.SearchResult{Entries: []*ldap.Entry{tc.entry}}, ch); err != nil {
+			t.Errorf("scraping %v failed: %s", tc.entry.Attributes, err)
+		} else if len(ch) != tc.expected {
+			t.Errorf("scraping %v produced %d metrics, expected %d", tc.entry.Attributes, len(ch), tc.expected)
+		}
+	}
+}
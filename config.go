@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 
-	"github.com/Masterminds/sprig"
 	"github.com/prometheus/common/log"
 
 	"gopkg.in/ldap.v2"
@@ -43,13 +45,51 @@ func (d *dnString) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal(&s); err != nil {
 		return err
 	}
-	if _, err := ldap.ParseDN(s); err != nil {
-		return fmt.Errorf("search is malformed: %s", err)
+	// templates are validated once rendered; see searchTemplate.
+	if !isSearchTemplate(s) {
+		if err := validateSearchBase(s); err != nil {
+			return fmt.Errorf("search is malformed: %s", err)
+		}
 	}
 	*d = (dnString)(s)
 	return nil
 }
 
+// searchBases accepts either a single search base or a list of them.
+type searchBases []dnString
+
+func (b *searchBases) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if _, ok := raw.([]interface{}); ok {
+		var bases []dnString
+		if err := unmarshal(&bases); err != nil {
+			return err
+		}
+		if len(bases) == 0 {
+			return fmt.Errorf("at least one search base must be given")
+		}
+		*b = bases
+		return nil
+	}
+	var base dnString
+	if err := unmarshal(&base); err != nil {
+		return err
+	}
+	*b = searchBases{base}
+	return nil
+}
+
+func (b searchBases) strings() []string {
+	bases := make([]string, len(b))
+	for idx, base := range b {
+		bases[idx] = string(base)
+	}
+	return bases
+}
+
 type filterString string
 
 func (f *filterString) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -57,8 +97,10 @@ func (f *filterString) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal(&s); err != nil {
 		return err
 	}
-	if _, err := ldap.CompileFilter(s); err != nil {
-		return fmt.Errorf("filter is malformed: %s", err)
+	if !isSearchTemplate(s) {
+		if err := validateSearchFilter(s); err != nil {
+			return fmt.Errorf("filter is malformed: %s", err)
+		}
 	}
 	*f = (filterString)(s)
 	return nil
@@ -73,7 +115,7 @@ func (t *templateString) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if err := unmarshal(&s); err != nil {
 		return err
 	}
-	new_t, err := template.New("config supplied template").Funcs((template.FuncMap)(sprig.FuncMap())).Parse(s)
+	new_t, err := template.New("config supplied template").Funcs(templateFuncs()).Parse(s)
 	if err != nil {
 		return fmt.Errorf("template parse failure; error was %s, template was:\n%s", err, s)
 	}
@@ -86,8 +128,29 @@ type metricAttributeConfig struct {
 	Type       string         `yaml:"type"`
 	Labels     []string       `yaml:"labels"`
 	Translator templateString `yaml:"translator"`
+	Extract    *extractConfig `yaml:"extract"`
+	CEL        *celConfig     `yaml:"cel"`
 	Help       string         `yaml:"help"`
 
+	// only valid for attribute patterns; if set, all matching attributes are exported as a
+	// single metric with this label holding the whole attribute name, or the pattern's first
+	// capture group if it has one.
+	AttributeLabel string `yaml:"attribute_label"`
+
+	// histogram bucket upper bounds; only valid for the histogram type.
+	Buckets []float64 `yaml:"buckets"`
+
+	// how to interpret the raw attribute value; see valueFormats.  Scale is multiplied against the result.
+	ValueFormat string  `yaml:"value_format"`
+	Scale       float64 `yaml:"scale"`
+	// export the seconds elapsed since the timestamp value, relative to the server's clock.
+	Age bool `yaml:"age"`
+
+	// for info and stateset types, the label holding the attribute's value or the state.
+	ValueLabel string `yaml:"value_label"`
+	// the enumerated values a stateset attribute can take.
+	States []string `yaml:"states"`
+
 	X map[string]interface{} `yaml:",inline"`
 }
 
@@ -106,6 +169,87 @@ func (mac *metricAttributeConfig) UnmarshalYAML(unmarshal func(interface{}) erro
 		return fmt.Errorf("type must be defined")
 	}
 
+	if err := validateBuckets(mac.Type, mac.Buckets); err != nil {
+		return err
+	}
+
+	if mac.CEL != nil {
+		if mac.Translator.template != nil || mac.Extract != nil {
+			return fmt.Errorf("cel is mutually exclusive with translator and extract")
+		}
+		if mac.ValueFormat != "" {
+			return fmt.Errorf("value_format cannot be used with cel; expressions must result in numeric values")
+		}
+		if err := mac.CEL.compile(celEnv); err != nil {
+			return err
+		}
+		if len(mac.Labels) == 0 {
+			mac.Labels = mac.CEL.labelNames()
+		} else {
+			for _, label := range mac.CEL.labelNames() {
+				if !containsString(mac.Labels, label) {
+					return fmt.Errorf("cel label %s must be listed in labels: %s", label, mac.Labels)
+				}
+			}
+		}
+	}
+
+	if mac.Extract != nil {
+		if mac.Translator.template != nil {
+			return fmt.Errorf("translator and extract are mutually exclusive")
+		}
+		if mac.ValueFormat != "" {
+			return fmt.Errorf("value_format cannot be used with extract; set it within extract instead")
+		}
+		if len(mac.Labels) == 0 {
+			mac.Labels = mac.Extract.labelNames()
+		} else {
+			for _, label := range mac.Extract.labelNames() {
+				if !containsString(mac.Labels, label) {
+					return fmt.Errorf("extract label %s must be listed in labels: %s", label, mac.Labels)
+				}
+			}
+		}
+	}
+
+	if mac.ValueFormat != "" {
+		if _, err := lookupValueFormat(mac.ValueFormat); err != nil {
+			return err
+		}
+		if mac.Translator.template != nil {
+			return fmt.Errorf("value_format cannot be used with translator; translators must emit numeric values")
+		}
+	}
+
+	if err := validateAge(mac.Age, mac.ValueFormat); err != nil {
+		return err
+	}
+
+	switch mac.Type {
+	case "counter":
+		if mac.Age {
+			return fmt.Errorf("ages can decrease, thus age cannot be used with counters")
+		}
+	case "info", "stateset":
+		if mac.Translator.template != nil || mac.Extract != nil || mac.CEL != nil {
+			return fmt.Errorf("%s metrics export the attribute's values directly; translator, extract, and cel cannot be used", mac.Type)
+		}
+		if mac.ValueFormat != "" || mac.Scale != 0 || mac.Age {
+			return fmt.Errorf("%s metrics export the attribute's values directly; value_format, scale, and age cannot be used", mac.Type)
+		}
+	default:
+		if mac.ValueLabel != "" {
+			return fmt.Errorf("value_label is only valid for info and stateset types")
+		}
+	}
+	if mac.Type == "stateset" {
+		if len(mac.States) == 0 {
+			return fmt.Errorf("stateset metrics require states to be defined")
+		}
+	} else if len(mac.States) != 0 {
+		return fmt.Errorf("states are only valid for the stateset type")
+	}
+
 	for idx, label := range mac.Labels {
 		if len(strings.TrimSpace(label)) != len(label) {
 			return fmt.Errorf("label at index %d cannot have whitespace and must be nonempty: '%s'", idx, label)
@@ -115,9 +259,39 @@ func (mac *metricAttributeConfig) UnmarshalYAML(unmarshal func(interface{}) erro
 	return nil
 }
 
+// metricAttributeConfigs allows a single attribute to feed multiple metrics.  For
+// compatibility either a single metric definition or a list of them is accepted.
+type metricAttributeConfigs []metricAttributeConfig
+
+func (m *metricAttributeConfigs) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if _, ok := raw.([]interface{}); ok {
+		var configs []metricAttributeConfig
+		if err := unmarshal(&configs); err != nil {
+			return err
+		}
+		if len(configs) == 0 {
+			return fmt.Errorf("at least one metric definition must be given")
+		}
+		*m = configs
+		return nil
+	}
+	var config metricAttributeConfig
+	if err := unmarshal(&config); err != nil {
+		return err
+	}
+	*m = metricAttributeConfigs{config}
+	return nil
+}
+
 type attributeConfig struct {
-	Labels  map[string]string                `yaml:"labels"`
-	Metrics map[string]metricAttributeConfig `yaml:"metrics"`
+	Labels  map[string]string                 `yaml:"labels"`
+	Metrics map[string]metricAttributeConfigs `yaml:"metrics"`
+	// keys are regexes matched against the attribute names returned by the server.
+	Patterns map[string]metricAttributeConfigs `yaml:"patterns"`
 
 	X map[string]interface{} `yaml:",inline"`
 }
@@ -135,6 +309,97 @@ func (a *attributeConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return nil
 }
 
+type aggregateConfig struct {
+	Name      string            `yaml:"metric_name"`
+	Type      string            `yaml:"type"`
+	Attribute string            `yaml:"attribute"`
+	GroupBy   map[string]string `yaml:"group_by"`
+	Buckets   []float64         `yaml:"buckets"`
+	Help      string            `yaml:"help"`
+
+	ValueFormat string  `yaml:"value_format"`
+	Scale       float64 `yaml:"scale"`
+	Age         bool    `yaml:"age"`
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (ac *aggregateConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain aggregateConfig
+
+	if err := unmarshal((*plain)(ac)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(ac.X, "config"); err != nil {
+		return err
+	}
+
+	switch ac.Type {
+	case "":
+		return fmt.Errorf("type must be defined")
+	case "count":
+		if ac.Attribute != "" || ac.ValueFormat != "" || ac.Scale != 0 || ac.Age {
+			return fmt.Errorf("count aggregates work on entries; attribute, value_format, scale, and age cannot be set")
+		}
+	case "sum", "min", "max", "avg", "histogram":
+		if ac.Attribute == "" {
+			return fmt.Errorf("%s aggregates require attribute to be set", ac.Type)
+		}
+	default:
+		return fmt.Errorf("aggregate type %s isn't valid; supported types are count, sum, min, max, avg, and histogram", ac.Type)
+	}
+
+	if err := validateBuckets(ac.Type, ac.Buckets); err != nil {
+		return err
+	}
+
+	if ac.ValueFormat != "" {
+		if _, err := lookupValueFormat(ac.ValueFormat); err != nil {
+			return err
+		}
+	}
+	if err := validateAge(ac.Age, ac.ValueFormat); err != nil {
+		return err
+	}
+
+	for attr, label := range ac.GroupBy {
+		if label == "" || len(strings.TrimSpace(label)) != len(label) {
+			return fmt.Errorf("group_by label for attribute %s cannot have whitespace and must be nonempty: '%s'", attr, label)
+		}
+	}
+	return nil
+}
+
+func validateAge(age bool, value_format string) error {
+	if !age {
+		return nil
+	}
+	switch value_format {
+	case "", "generalized_time", "windows_filetime":
+		return nil
+	}
+	return fmt.Errorf("age requires a timestamp value_format, not %s", value_format)
+}
+
+func validateBuckets(metric_type string, buckets []float64) error {
+	if metric_type != "histogram" {
+		if len(buckets) != 0 {
+			return fmt.Errorf("buckets are only valid for the histogram type")
+		}
+		return nil
+	}
+	if len(buckets) == 0 {
+		return fmt.Errorf("histograms require buckets to be defined")
+	}
+	for idx := 1; idx < len(buckets); idx++ {
+		if buckets[idx] <= buckets[idx-1] {
+			return fmt.Errorf("histogram buckets must be in increasing order: %v", buckets)
+		}
+	}
+	return nil
+}
+
 type scopeChoice int
 
 func (s *scopeChoice) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -191,18 +456,48 @@ func (d *derefChoice) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 type metricSourceConfig struct {
 	Name   string        `yaml:"name"`
-	Search *dnString     `yaml:"search"`
+	Search searchBases   `yaml:"search"`
 	Filter *filterString `yaml:"filter"`
 	Scope  *scopeChoice  `yaml:"scope"`
 	Deref  *derefChoice  `yaml:"deref"`
 
+	// an RFC 4516 LDAP URL; an alternative to search, filter, and scope.
+	URL string `yaml:"url"`
+
+	// search every naming context the rootDSE lists, rather than the configured bases.
+	NamingContexts bool `yaml:"naming_contexts"`
+	// the label holding the search base; defaults to base if there's more than one.
+	BaseLabel string `yaml:"base_label"`
+	// request controls sent with the search.
+	Controls []controlConfig `yaml:"controls"`
+	// run the source as its own identity rather than -ldap.bind's.
+	Bind *bindConfig `yaml:"bind"`
+	// run the source's searches as another identity via proxied authorization.
+	ProxyAuthz *proxyAuthz `yaml:"proxy_authz"`
+
 	CounterNameTemplate *templateString   `yaml:"counter_metric_name_template"`
 	GaugeNameTemplate   *templateString   `yaml:"gauge_metric_name_template"`
 	Attributes          attributeConfig   `yaml:"attributes"`
 	ConstantLabels      map[string]string `yaml:"labels"`
 
+	// metrics computed over all entries the search returns, rather than per entry.
+	Aggregates map[string]aggregateConfig `yaml:"aggregates"`
+	// metrics computed from several attributes of each entry.
+	Derived map[string]derivedConfig `yaml:"derived"`
+	// checks that the search returns what's expected.
+	Assertions map[string]assertionConfig `yaml:"assertions"`
+	// labels copied from the entries of a second search.
+	Join *joinConfig `yaml:"join"`
+
 	labelsFromAttributes []string
-	metricAttributes     map[string]MetricAttribute
+	metricAttributes     map[string][]MetricAttribute
+	patternAttributes    []*patternMetricAttribute
+	sourceMetrics        []SourceMetric
+	needsReferenceTime   bool
+	templates            searchTemplates
+	urlAttributes        []string
+	// attributes cel expressions reference beyond the one being translated.
+	celAttributes []string
 
 	X map[string]interface{} `yaml:",inline"`
 }
@@ -219,13 +514,34 @@ func (s *metricSourceConfig) UnmarshalYAML(unmarshal func(interface{}) error) er
 		return err
 	}
 
-	if s.Search == nil {
+	if s.URL != "" {
+		if len(s.Search) != 0 || s.Filter != nil || s.Scope != nil || s.NamingContexts {
+			return fmt.Errorf("url is mutually exclusive with search, filter, scope, and naming_contexts")
+		}
+		if err := s.applyURL(); err != nil {
+			return err
+		}
+	}
+	if s.NamingContexts {
+		if len(s.Search) != 0 {
+			return fmt.Errorf("search and naming_contexts are mutually exclusive")
+		}
+		if s.BaseLabel == "" {
+			s.BaseLabel = "base"
+		}
+	} else if len(s.Search) == 0 {
 		return fmt.Errorf("search is either empty or undefined")
+	} else if len(s.Search) > 1 && s.BaseLabel == "" {
+		s.BaseLabel = "base"
 	}
 	if s.Filter == nil {
 		var f = "(objectClass=*)"
 		s.Filter = (*filterString)(&f)
 	}
+	var err error
+	if s.templates, err = newSearchTemplates(s.Search.strings(), string(*s.Filter)); err != nil {
+		return err
+	}
 	if s.CounterNameTemplate == nil {
 		s.CounterNameTemplate = defaultCounterNameTemplate
 	}
@@ -252,7 +568,13 @@ func (s *metricSourceConfig) UnmarshalYAML(unmarshal func(interface{}) error) er
 		}
 	}
 
-	s.metricAttributes = make(map[string]MetricAttribute)
+	s.metricAttributes = make(map[string][]MetricAttribute)
+	if s.BaseLabel != "" {
+		if len(strings.TrimSpace(s.BaseLabel)) != len(s.BaseLabel) {
+			return fmt.Errorf("base_label cannot have whitespace: '%s'", s.BaseLabel)
+		}
+		s.labelsFromAttributes = append(s.labelsFromAttributes, s.BaseLabel)
+	}
 	for src, final_name := range s.Attributes.Labels {
 		for _, v := range s.labelsFromAttributes {
 			if final_name == v {
@@ -261,19 +583,243 @@ func (s *metricSourceConfig) UnmarshalYAML(unmarshal func(interface{}) error) er
 		}
 		s.labelsFromAttributes = append(s.labelsFromAttributes, final_name)
 	}
-	for attr, metric_config := range s.Attributes.Metrics {
-		if err := checkOverflow(metric_config.X, fmt.Sprintf("attribute %s", attr)); err != nil {
+	if s.Join != nil {
+		for _, label := range s.Join.join.labelNames() {
+			if containsString(s.labelsFromAttributes, label) {
+				return fmt.Errorf("join label %s conflicts with an attribute label of the same name", label)
+			}
+			s.labelsFromAttributes = append(s.labelsFromAttributes, label)
+		}
+	}
+	metricNames := make(map[string]string)
+	for attr, metric_configs := range s.Attributes.Metrics {
+		for _, metric_config := range metric_configs {
+			if err := checkOverflow(metric_config.X, fmt.Sprintf("attribute %s", attr)); err != nil {
+				return err
+			}
+			if err := s.createMetricAttribute(&metric_config, attr); err != nil {
+				return err
+			}
+			if other, ok := metricNames[metric_config.Name]; ok {
+				return fmt.Errorf("attributes %s and %s both define metric %s; use metric_name to disambiguate", other, attr, metric_config.Name)
+			}
+			metricNames[metric_config.Name] = attr
+		}
+	}
+
+	// sort the patterns so the order they're applied in is stable across runs.
+	var patterns []string
+	for pattern := range s.Attributes.Patterns {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	generated := &patternNames{names: make(map[string]string)}
+	for _, pattern := range patterns {
+		for _, metric_config := range s.Attributes.Patterns[pattern] {
+			if err := checkOverflow(metric_config.X, fmt.Sprintf("attribute pattern %s", pattern)); err != nil {
+				return err
+			}
+			if err := s.createPatternMetricAttribute(&metric_config, pattern, metricNames, generated); err != nil {
+				return err
+			}
+		}
+	}
+
+	var aggregates []string
+	for name := range s.Aggregates {
+		aggregates = append(aggregates, name)
+	}
+	sort.Strings(aggregates)
+	for _, name := range aggregates {
+		aggregate_config := s.Aggregates[name]
+		if err := s.createAggregateMetric(&aggregate_config, name); err != nil {
 			return err
 		}
-		if err := s.createMetricAttribute(&metric_config, attr); err != nil {
+		if other, ok := metricNames[aggregate_config.Name]; ok {
+			return fmt.Errorf("aggregate %s conflicts with %s; both define metric %s, use metric_name to disambiguate", name, other, aggregate_config.Name)
+		}
+		metricNames[aggregate_config.Name] = name
+	}
+
+	var derived []string
+	for name := range s.Derived {
+		derived = append(derived, name)
+	}
+	sort.Strings(derived)
+	for _, name := range derived {
+		derived_config := s.Derived[name]
+		if err := s.createDerivedMetric(&derived_config, name); err != nil {
 			return err
 		}
+		if other, ok := metricNames[derived_config.Name]; ok {
+			return fmt.Errorf("derived metric %s conflicts with %s; both define metric %s, use metric_name to disambiguate", name, other, derived_config.Name)
+		}
+		metricNames[derived_config.Name] = name
+	}
+
+	if len(s.Assertions) != 0 && s.BaseLabel != "" {
+		return fmt.Errorf("assertions can't be used with multiple search bases or naming_contexts")
+	}
+	var assertions []string
+	for name := range s.Assertions {
+		assertions = append(assertions, name)
+	}
+	sort.Strings(assertions)
+	for _, name := range assertions {
+		assertion_config := s.Assertions[name]
+		s.sourceMetrics = append(s.sourceMetrics, (SourceMetric)(NewAssertionMetric(name, &assertion_config)))
 	}
 
 	return nil
 }
 
+// applyURL populates the search, filter, and scope from the source's url.  Any attributes
+// it lists are requested in addition to those the metrics need.
+func (s *metricSourceConfig) applyURL() error {
+	u, err := parseLDAPURL(s.URL)
+	if err != nil {
+		return err
+	}
+	if u.Host != "" {
+		return fmt.Errorf("url %s names host %s; sources always use the exporter's connection, thus the host must be empty", s.URL, u.Host)
+	}
+	for _, ext := range u.Extensions {
+		if ext.Critical {
+			return fmt.Errorf("url %s has critical extension %s, which isn't supported", s.URL, ext.Type)
+		}
+		log.Warnf("section %s: ignoring unsupported url extension %s", s.Name, ext.Type)
+	}
+	if !isSearchTemplate(u.DN) {
+		if err := validateSearchBase(u.DN); err != nil {
+			return fmt.Errorf("url dn is malformed: %s", err)
+		}
+	}
+	s.Search = searchBases{dnString(u.DN)}
+	if u.Filter != "" {
+		if !isSearchTemplate(u.Filter) {
+			if err := validateSearchFilter(u.Filter); err != nil {
+				return fmt.Errorf("url filter is malformed: %s", err)
+			}
+		}
+		s.Filter = (*filterString)(&u.Filter)
+	}
+	if u.Scope >= 0 {
+		s.Scope = (*scopeChoice)(&u.Scope)
+	}
+	s.urlAttributes = u.Attributes
+	return nil
+}
+
 func (msc *metricSourceConfig) createMetricAttribute(a *metricAttributeConfig, attribute string) error {
+	if a.AttributeLabel != "" {
+		return fmt.Errorf("attribute %s: attribute_label is only valid for attribute patterns", attribute)
+	}
+	metric, err := msc.newMetricAttribute(a, attribute)
+	if err != nil {
+		return err
+	}
+	if a.CEL != nil {
+		msc.celAttributes = append(msc.celAttributes, a.CEL.translator.attributes()...)
+	}
+	msc.metricAttributes[attribute] = append(msc.metricAttributes[attribute], metric)
+	return nil
+}
+
+// patternNames tracks the metric names patterns create at scrape time, so two can't collide.
+type patternNames struct {
+	lock  sync.Mutex
+	names map[string]string
+}
+
+func (n *patternNames) claim(name string, owner string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if other, ok := n.names[name]; ok && other != owner {
+		return fmt.Errorf("%s and %s both define metric %s", other, owner, name)
+	}
+	n.names[name] = owner
+	return nil
+}
+
+// createPatternMetricAttribute checks the names of the metrics patterns create against
+// metric_names, which holds the source's other metrics; it must not be modified once the
+// source is loaded, since it's read at scrape time.
+func (msc *metricSourceConfig) createPatternMetricAttribute(a *metricAttributeConfig, pattern string, metric_names map[string]string, generated *patternNames) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("attribute pattern %s is malformed: %s", pattern, err)
+	}
+	if a.Help == "" {
+		// warn once here rather than for every attribute the pattern matches.
+		log.Warnf("section %s, attribute pattern %s: no help provided", msc.Name, pattern)
+		a.Help = "No help provided"
+	}
+	p := &patternMetricAttribute{
+		pattern:        re,
+		attributeLabel: a.AttributeLabel,
+	}
+	if a.AttributeLabel != "" {
+		// everything matching lands in a single metric, thus a name can't be derived from the attribute.
+		if a.Name == "" {
+			return fmt.Errorf("attribute pattern %s: metric_name must be set when attribute_label is used", pattern)
+		}
+		if p.metric, err = msc.newMetricAttribute(a, pattern, a.AttributeLabel); err != nil {
+			return err
+		}
+		owner := fmt.Sprintf("attribute pattern %s", pattern)
+		if other, ok := metric_names[a.Name]; ok {
+			return fmt.Errorf("%s conflicts with %s; both define metric %s", owner, other, a.Name)
+		}
+		metric_names[a.Name] = owner
+	} else {
+		if a.Name != "" {
+			return fmt.Errorf("attribute pattern %s: metric_name can't be used without attribute_label; use the source's name templates instead", pattern)
+		}
+		// validate the configuration now rather than at scrape time.
+		config := *a
+		if _, err := msc.newMetricAttribute(&config, "validation"); err != nil {
+			return fmt.Errorf("attribute pattern %s: %s", pattern, err)
+		}
+		// a is the caller's loop variable, so copy it now rather than when the factory runs.
+		base := *a
+		p.factory = func(attribute string) (MetricAttribute, error) {
+			config := base
+			metric, err := msc.newMetricAttribute(&config, attribute)
+			if err != nil {
+				return nil, err
+			}
+			owner := fmt.Sprintf("attribute %s via pattern %s", attribute, pattern)
+			if other, ok := metric_names[config.Name]; ok {
+				return nil, fmt.Errorf("%s conflicts with %s; both define metric %s", owner, other, config.Name)
+			}
+			if err := generated.claim(config.Name, owner); err != nil {
+				return nil, err
+			}
+			return metric, nil
+		}
+	}
+	if a.CEL != nil {
+		msc.celAttributes = append(msc.celAttributes, a.CEL.translator.attributes()...)
+	}
+	msc.patternAttributes = append(msc.patternAttributes, p)
+	return nil
+}
+
+// metricName returns the final metric name; if name is empty, it's derived from the given template.
+func (msc *metricSourceConfig) metricName(t *templateString, name string, attribute string) (string, error) {
+	if name == "" {
+		var buffer bytes.Buffer
+		if err := t.template.Option("missingkey=error").Execute(&buffer, map[string]string{"section": msc.Name, "attribute": attribute}); err != nil {
+			return "", fmt.Errorf("attribute %s naming error: %s", attribute, err)
+		}
+		log.Debugf("templating metric name for attr %s to %s", attribute, buffer.String())
+		// attribute names can contain characters like '-' that aren't valid in metric names.
+		name = sanitizeMetricName(buffer.String())
+	}
+	return fmt.Sprintf("ldap_%s", name), nil
+}
+
+func (msc *metricSourceConfig) newMetricAttribute(a *metricAttributeConfig, attribute string, extra_labels ...string) (MetricAttribute, error) {
 	help := a.Help
 	if help == "" {
 		log.Warnf("section %s, attribute %s: no help provided", msc.Name, attribute)
@@ -281,50 +827,198 @@ func (msc *metricSourceConfig) createMetricAttribute(a *metricAttributeConfig, a
 	}
 
 	setName := func(t *templateString) error {
-		if a.Name == "" {
-			var buffer bytes.Buffer
-			if err := t.template.Option("missingkey=error").Execute(&buffer, map[string]string{"section": msc.Name, "attribute": attribute}); err != nil {
-				return fmt.Errorf("attribute %s naming error: %s", attribute, err)
-			}
-			log.Debugf("templating metric name for attr %s to %s", attribute, buffer.String())
-			a.Name = buffer.String()
+		name, err := msc.metricName(t, a.Name, attribute)
+		if err != nil {
+			return err
 		}
-		a.Name = fmt.Sprintf("ldap_%s", a.Name)
+		a.Name = name
 		return nil
 	}
 
 	labels := a.Labels
-	if len(msc.labelsFromAttributes) != 0 {
+	if len(msc.labelsFromAttributes) != 0 || len(extra_labels) != 0 {
 		labels = []string{}
 		labels = append(labels, msc.labelsFromAttributes...)
+		labels = append(labels, extra_labels...)
 		labels = append(labels, a.Labels...)
 	}
+	// counters historically only accepted integers, thus the differing defaults.
+	default_parser := parseNumber
+	if a.Type == "counter" {
+		default_parser = parseUnsigned
+	}
+	var translator valueTranslator
+	if a.Translator.template != nil {
+		translator = &templateTranslator{template: a.Translator.template}
+	} else if a.Extract != nil {
+		translator = a.Extract.translator
+	} else if a.CEL != nil {
+		translator = a.CEL.translator
+	}
+
+	// translators emit unix timestamps, so only default the format for raw values.
+	value_format := a.ValueFormat
+	if a.Age && translator != nil {
+		value_format = "number"
+	}
+	converter, err := newValueConverter(value_format, a.Scale, a.Age, default_parser)
+	if err != nil {
+		return nil, err
+	}
+	if a.Age {
+		msc.needsReferenceTime = true
+	}
+
 	switch a.Type {
 	case "counter":
 		if err := setName(msc.CounterNameTemplate); err != nil {
-			return err
+			return nil, err
 		}
-		msc.metricAttributes[attribute] = (MetricAttribute)(NewCounterMetricAttribute(
+		return (MetricAttribute)(NewCounterMetricAttribute(
 			a.Name,
 			labels,
 			msc.ConstantLabels,
-			a.Translator.template,
+			translator,
+			converter,
 			help,
-		))
+		)), nil
 	case "gauge":
 		if err := setName(msc.GaugeNameTemplate); err != nil {
-			return err
+			return nil, err
 		}
-		msc.metricAttributes[attribute] = (MetricAttribute)(NewGaugeMetricAttribute(
+		return (MetricAttribute)(NewGaugeMetricAttribute(
 			a.Name,
 			labels,
 			msc.ConstantLabels,
-			a.Translator.template,
+			translator,
+			converter,
 			help,
-		))
-	default:
-		return fmt.Errorf("type %s isn't valid for attribute %s", a.Type, attribute)
+		)), nil
+	case "histogram":
+		if err := setName(msc.GaugeNameTemplate); err != nil {
+			return nil, err
+		}
+		return (MetricAttribute)(NewHistogramMetricAttribute(
+			a.Name,
+			labels,
+			msc.ConstantLabels,
+			translator,
+			converter,
+			a.Buckets,
+			help,
+		)), nil
+	case "info", "stateset":
+		if err := setName(msc.GaugeNameTemplate); err != nil {
+			return nil, err
+		}
+		value_label := a.ValueLabel
+		if value_label == "" {
+			value_label = sanitizeMetricName(attribute)
+		}
+		if _, ok := msc.ConstantLabels[value_label]; ok || containsString(labels, value_label) {
+			return nil, fmt.Errorf("attribute %s: value label %s conflicts with an existing label; set value_label to disambiguate", attribute, value_label)
+		}
+		labels = append(append([]string{}, labels...), value_label)
+		if a.Type == "info" {
+			return (MetricAttribute)(NewInfoMetricAttribute(
+				a.Name,
+				labels,
+				msc.ConstantLabels,
+				help,
+			)), nil
+		}
+		return (MetricAttribute)(NewStateSetMetricAttribute(
+			a.Name,
+			labels,
+			msc.ConstantLabels,
+			a.States,
+			help,
+		)), nil
 	}
+	return nil, fmt.Errorf("type %s isn't valid for attribute %s", a.Type, attribute)
+}
+
+func (msc *metricSourceConfig) createAggregateMetric(a *aggregateConfig, name string) error {
+	help := a.Help
+	if help == "" {
+		log.Warnf("section %s, aggregate %s: no help provided", msc.Name, name)
+		help = "No help provided"
+	}
+	metric_name, err := msc.metricName(msc.GaugeNameTemplate, a.Name, name)
+	if err != nil {
+		return err
+	}
+	a.Name = metric_name
+
+	// sort so the label ordering is stable.
+	var group_attributes []string
+	for attr := range a.GroupBy {
+		group_attributes = append(group_attributes, attr)
+	}
+	sort.Strings(group_attributes)
+	var source_labels []string
+	if msc.BaseLabel != "" {
+		source_labels = append(source_labels, msc.BaseLabel)
+	}
+	labels := append([]string{}, source_labels...)
+	for _, attr := range group_attributes {
+		if containsString(labels, a.GroupBy[attr]) {
+			return fmt.Errorf("aggregate %s: group_by label %s is already a label", name, a.GroupBy[attr])
+		}
+		labels = append(labels, a.GroupBy[attr])
+	}
+
+	converter, err := newValueConverter(a.ValueFormat, a.Scale, a.Age, parseNumber)
+	if err != nil {
+		return err
+	}
+	if a.Age {
+		msc.needsReferenceTime = true
+	}
+
+	msc.sourceMetrics = append(msc.sourceMetrics, (SourceMetric)(NewAggregateMetric(
+		a.Name,
+		a.Type,
+		a.Attribute,
+		source_labels,
+		group_attributes,
+		labels,
+		msc.ConstantLabels,
+		converter,
+		a.Buckets,
+		help,
+	)))
+	return nil
+}
+
+func (msc *metricSourceConfig) createDerivedMetric(d *derivedConfig, name string) error {
+	help := d.Help
+	if help == "" {
+		log.Warnf("section %s, derived metric %s: no help provided", msc.Name, name)
+		help = "No help provided"
+	}
+	name_template := msc.GaugeNameTemplate
+	if d.Type == "counter" {
+		name_template = msc.CounterNameTemplate
+	}
+	metric_name, err := msc.metricName(name_template, d.Name, name)
+	if err != nil {
+		return err
+	}
+	d.Name = metric_name
+
+	labels := append(append([]string{}, msc.labelsFromAttributes...), d.Labels...)
+	msc.sourceMetrics = append(msc.sourceMetrics, (SourceMetric)(NewDerivedMetric(
+		d.Name,
+		d.Type,
+		labels,
+		msc.ConstantLabels,
+		d.required,
+		d.optional,
+		d.translator,
+		d.Scale,
+		help,
+	)))
 	return nil
 }
 
@@ -336,8 +1030,44 @@ func LoadConfig(data string) ([]*MetricsSource, error) {
 
 	var sources []*MetricsSource
 
+	assertions := make(map[string]string)
 	for _, section := range parsed_data {
-		sources = append(sources, NewMetricsSource((*string)(section.Search), (*string)(section.Filter), (int)(*section.Scope), (int)(*section.Deref), section.metricAttributes, section.Attributes.Labels))
+		for name := range section.Assertions {
+			if other, ok := assertions[name]; ok {
+				return nil, fmt.Errorf("sections %s and %s both define assertion %s; assertion names must be unique", other, section.Name, name)
+			}
+			assertions[name] = section.Name
+		}
+		// naming contexts are only known at scrape time.
+		base := ""
+		if len(section.Search) != 0 {
+			base = string(section.Search[0])
+		}
+		source := NewMetricsSource(&base, (*string)(section.Filter), (int)(*section.Scope), (int)(*section.Deref), section.metricAttributes, section.patternAttributes, section.sourceMetrics, section.Attributes.Labels)
+		source.NeedsReferenceTime = section.needsReferenceTime
+		source.templates = section.templates
+		if len(section.Search) > 1 {
+			source.Bases = section.Search.strings()
+		}
+		source.NamingContexts = section.NamingContexts
+		source.BaseLabel = section.BaseLabel
+		source.Name = section.Name
+		source.addAttributes(section.urlAttributes...)
+		source.addAttributes(section.celAttributes...)
+		for _, control := range section.Controls {
+			source.addControl(control.control(), false)
+		}
+		if section.Join != nil {
+			source.addJoin(section.Join.join)
+		}
+		if section.ProxyAuthz != nil {
+			// the join is run as the same identity so its labels reflect what it can see.
+			source.addControl(section.ProxyAuthz.control(), true)
+		}
+		if section.Bind != nil {
+			source.Bind = section.Bind.identity()
+		}
+		sources = append(sources, source)
 	}
 	return sources, nil
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	ber "gopkg.in/asn1-ber.v1"
+	"gopkg.in/ldap.v2"
+)
+
+// berTrue is a BER encoded BOOLEAN TRUE, the value several controls take.
+const berTrue = "\x01\x01\xff"
+
+// namedControls are shortcuts for common controls.  Note 389 needs no control for
+// tombstones; search for (objectClass=nsTombstone) instead.
+var namedControls = map[string]controlConfig{
+	// RFC 3296; return referral objects rather than following them.
+	"manage_dsa_it": {OID: "2.16.840.1.113730.3.4.2"},
+	// RFC 3672; return subentries such as password policies.
+	"subentries": {OID: "1.3.6.1.4.1.4203.1.10.1", Value: stringPtr(berTrue)},
+	// active directory; include deleted objects.
+	"show_deleted": {OID: "1.2.840.113556.1.4.417"},
+	// active directory; include recycled objects.
+	"show_recycled": {OID: "1.2.840.113556.1.4.2064"},
+	// active directory; don't generate referrals to other domains.
+	"domain_scope": {OID: "1.2.840.113556.1.4.1339"},
+}
+
+// controlTypeProxiedAuthz is RFC 4370's proxied authorization v2 control.
+const controlTypeProxiedAuthz = "2.16.840.1.113730.3.4.18"
+
+var oidRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)+$`)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// controlConfig is a request control given either as a name from namedControls or an OID,
+// or as a mapping allowing the criticality and value to be set.  The value is either a
+// string or, for BER encoded values, value_hex.
+type controlConfig struct {
+	Name        string  `yaml:"name"`
+	OID         string  `yaml:"oid"`
+	Criticality bool    `yaml:"criticality"`
+	Value       *string `yaml:"value"`
+	ValueHex    string  `yaml:"value_hex"`
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (c *controlConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain controlConfig
+
+	var shorthand string
+	if err := unmarshal(&shorthand); err == nil {
+		if oidRegex.MatchString(shorthand) {
+			c.OID = shorthand
+		} else {
+			c.Name = shorthand
+		}
+	} else if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.X, "control"); err != nil {
+		return err
+	}
+
+	if c.Name != "" {
+		if c.OID != "" {
+			return fmt.Errorf("control name and oid are mutually exclusive")
+		}
+		named, ok := namedControls[c.Name]
+		if !ok {
+			var names []string
+			for name := range namedControls {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("control %s is unknown; supported names are %s, otherwise give the oid", c.Name, strings.Join(names, ", "))
+		}
+		c.OID = named.OID
+		if c.Value == nil && c.ValueHex == "" {
+			c.Value = named.Value
+		}
+	}
+	if !oidRegex.MatchString(c.OID) {
+		return fmt.Errorf("control oid %q is malformed", c.OID)
+	}
+	if c.ValueHex != "" {
+		if c.Value != nil {
+			return fmt.Errorf("control value and value_hex are mutually exclusive")
+		}
+		decoded, err := hex.DecodeString(c.ValueHex)
+		if err != nil {
+			return fmt.Errorf("control value_hex is malformed: %s", err)
+		}
+		c.Value = stringPtr(string(decoded))
+	}
+	return nil
+}
+
+func (c *controlConfig) control() ldap.Control {
+	return &requestControl{oid: c.OID, criticality: c.Criticality, value: c.Value}
+}
+
+// requestControl differs from ldap.ControlString in omitting the value entirely when
+// unset; controls such as ManageDsaIT are rejected if an empty value is present.
+type requestControl struct {
+	oid         string
+	criticality bool
+	value       *string
+}
+
+func (c *requestControl) GetControlType() string {
+	return c.oid
+}
+
+func (c *requestControl) Encode() *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, c.oid, "Control Type ("+ldap.ControlTypeMap[c.oid]+")"))
+	if c.criticality {
+		packet.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, c.criticality, "Criticality"))
+	}
+	if c.value != nil {
+		packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, *c.value, "Control Value"))
+	}
+	return packet
+}
+
+func (c *requestControl) String() string {
+	value := "<absent>"
+	if c.value != nil {
+		value = fmt.Sprintf("%q", *c.value)
+	}
+	return fmt.Sprintf("Control Type: %s (%q)  Criticality: %t  Control Value: %s", ldap.ControlTypeMap[c.oid], c.oid, c.criticality, value)
+}
+
+// proxyAuthz is the identity a source's searches are run as via proxied authorization;
+// either an RFC 4513 authzId (dn: or u: prefixed) or a bare dn.  The bound identity must
+// be permitted to proxy, for 389 via a proxy ACI and for OpenLDAP via authzTo.
+type proxyAuthz string
+
+func (p *proxyAuthz) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	switch {
+	case strings.HasPrefix(s, "dn:"):
+		// an empty dn is the anonymous identity.
+		if _, err := ldap.ParseDN(s[3:]); err != nil {
+			return fmt.Errorf("proxy_authz %q is malformed: %s", s, err)
+		}
+	case strings.HasPrefix(s, "u:"):
+		if s == "u:" {
+			return fmt.Errorf("proxy_authz %q lacks a user", s)
+		}
+	case s == "":
+		return fmt.Errorf("proxy_authz cannot be empty; use dn: for the anonymous identity")
+	default:
+		if _, err := ldap.ParseDN(s); err != nil {
+			return fmt.Errorf("proxy_authz %q is neither a dn nor an authzId: %s", s, err)
+		}
+		s = "dn:" + s
+	}
+	*p = proxyAuthz(s)
+	return nil
+}
+
+// control builds the proxied authorization control; RFC 4370 requires it be critical so a
+// server not honoring it can't run the search as the bound identity instead.
+func (p proxyAuthz) control() ldap.Control {
+	return &requestControl{oid: controlTypeProxiedAuthz, criticality: true, value: stringPtr(string(p))}
+}
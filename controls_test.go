@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestControls(t *testing.T) {
+	for config, expected := range map[string]string{
+		// absent values are omitted entirely rather than encoded as empty.
+		`manage_dsa_it`:           "\x30\x19\x04\x172.16.840.1.113730.3.4.2",
+		`2.16.840.1.113730.3.4.2`: "\x30\x19\x04\x172.16.840.1.113730.3.4.2",
+		`subentries`:              "\x30\x1e\x04\x171.3.6.1.4.1.4203.1.10.1\x04\x03\x01\x01\xff",
+		`{name: domain_scope, criticality: true}`: "\x30\x1c\x04\x171.2.840.113556.1.4.1339\x01\x01\x01",
+		`{oid: 1.2.3, value: abc}`:                "\x30\x0c\x04\x051.2.3\x04\x03abc",
+		`{oid: 1.2.3, value: ""}`:                 "\x30\x09\x04\x051.2.3\x04\x00",
+		`{oid: 1.2.3, value_hex: "3003020101"}`:   "\x30\x0e\x04\x051.2.3\x04\x05\x30\x03\x02\x01\x01",
+		`{name: subentries, value_hex: "010100"}`: "\x30\x1e\x04\x171.3.6.1.4.1.4203.1.10.1\x04\x03\x01\x01\x00",
+	} {
+		var control controlConfig
+		if err := yaml.Unmarshal([]byte(config), &control); err != nil {
+			t.Errorf("control %s failed: %s", config, err)
+		} else if encoded := string(control.control().Encode().Bytes()); encoded != expected {
+			t.Errorf("control %s encoded to %q, expected %q", config, encoded, expected)
+		}
+	}
+	for _, config := range []string{
+		`nope`,
+		`1`,
+		`{oid: "1.2.x"}`,
+		`{name: subentries, oid: 1.2.3}`,
+		`{oid: 1.2.3, value: a, value_hex: "00"}`,
+		`{oid: 1.2.3, value_hex: "zz"}`,
+		`{oid: 1.2.3, bogus: 1}`,
+	} {
+		var control controlConfig
+		if err := yaml.Unmarshal([]byte(config), &control); err == nil {
+			t.Errorf("control %s should fail", config)
+		}
+	}
+}
+
+func TestProxyAuthz(t *testing.T) {
+	// RFC 4370; the value is the authzId itself rather than BER, and the control is critical.
+	for config, expected := range map[string]string{
+		`"dn:cn=a,dc=example"`: "\x30\x31\x04\x182.16.840.1.113730.3.4.18\x01\x01\x01\x04\x12dn:cn=a,dc=example",
+		`"cn=a,dc=example"`:    "\x30\x31\x04\x182.16.840.1.113730.3.4.18\x01\x01\x01\x04\x12dn:cn=a,dc=example",
+		`"u:alice"`:            "\x30\x26\x04\x182.16.840.1.113730.3.4.18\x01\x01\x01\x04\x07u:alice",
+		// the anonymous identity.
+		`"dn:"`: "\x30\x22\x04\x182.16.840.1.113730.3.4.18\x01\x01\x01\x04\x03dn:",
+	} {
+		var proxy proxyAuthz
+		if err := yaml.Unmarshal([]byte(config), &proxy); err != nil {
+			t.Errorf("proxy_authz %s failed: %s", config, err)
+		} else if encoded := string(proxy.control().Encode().Bytes()); encoded != expected {
+			t.Errorf("proxy_authz %s encoded to %q, expected %q", config, encoded, expected)
+		}
+	}
+	for _, config := range []string{`""`, `"u:"`, `"dn:not a dn"`, `"not a dn"`} {
+		var proxy proxyAuthz
+		if err := yaml.Unmarshal([]byte(config), &proxy); err == nil {
+			t.Errorf("proxy_authz %s should fail", config)
+		}
+	}
+}
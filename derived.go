@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/ldap.v2"
+)
+
+// derivedConfig is a metric computed from several attributes of the same entry, for
+// example a ratio or difference.  Either cel or template is used; the template is given
+// .attributes (name -> values) and .dn, and like translators either emits results or
+// renders yaml.  The attributes referenced are requested automatically; attributes lists
+// any the references can't be determined for.  Entries lacking a required attribute are skipped.
+type derivedConfig struct {
+	Name       string         `yaml:"metric_name"`
+	Type       string         `yaml:"type"`
+	Labels     []string       `yaml:"labels"`
+	CEL        *celConfig     `yaml:"cel"`
+	Template   templateString `yaml:"template"`
+	Attributes []string       `yaml:"attributes"`
+	Scale      float64        `yaml:"scale"`
+	Help       string         `yaml:"help"`
+
+	translator derivedTranslator
+	required   []string
+	optional   []string
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (dc *derivedConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain derivedConfig
+
+	if err := unmarshal((*plain)(dc)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(dc.X, "config"); err != nil {
+		return err
+	}
+
+	switch dc.Type {
+	case "":
+		return fmt.Errorf("type must be defined")
+	case "counter", "gauge":
+	default:
+		return fmt.Errorf("derived type %s isn't valid; supported types are counter and gauge", dc.Type)
+	}
+
+	if dc.CEL != nil {
+		if dc.Template.template != nil {
+			return fmt.Errorf("derived metrics require exactly one of cel or template")
+		}
+		if err := dc.CEL.compile(celDerivedEnv); err != nil {
+			return err
+		}
+		dc.translator = dc.CEL.translator
+		dc.required, dc.optional = dc.CEL.translator.required, dc.CEL.translator.optional
+		if len(dc.Labels) == 0 {
+			dc.Labels = dc.CEL.labelNames()
+		} else {
+			for _, label := range dc.CEL.labelNames() {
+				if !containsString(dc.Labels, label) {
+					return fmt.Errorf("cel label %s must be listed in labels: %s", label, dc.Labels)
+				}
+			}
+		}
+	} else if dc.Template.template != nil {
+		dc.translator = &derivedTemplate{template: dc.Template.template}
+		dc.required = templateAttributeReferences(dc.Template.template.Templates()...)
+	} else {
+		return fmt.Errorf("derived metrics require exactly one of cel or template")
+	}
+
+	for _, attr := range dc.Attributes {
+		if attr == "" || len(strings.TrimSpace(attr)) != len(attr) {
+			return fmt.Errorf("derived attribute cannot have whitespace and must be nonempty: '%s'", attr)
+		}
+		if !containsString(dc.required, attr) {
+			dc.required = append(dc.required, attr)
+		}
+	}
+	if len(dc.required) == 0 && len(dc.optional) == 0 {
+		return fmt.Errorf("derived metric references no attributes; list them via attributes")
+	}
+
+	for idx, label := range dc.Labels {
+		if len(strings.TrimSpace(label)) != len(label) {
+			return fmt.Errorf("label at index %d cannot have whitespace and must be nonempty: '%s'", idx, label)
+		}
+	}
+	return nil
+}
+
+// templateAttributeReferences finds the .attributes.name and index .attributes "name"
+// references in the templates.
+func templateAttributeReferences(templates ...*template.Template) []string {
+	seen := make(map[string]bool)
+	isAttributes := func(n parse.Node) bool {
+		switch n := n.(type) {
+		case *parse.FieldNode:
+			return len(n.Ident) == 1 && n.Ident[0] == "attributes"
+		case *parse.VariableNode:
+			return len(n.Ident) == 2 && n.Ident[0] == "$" && n.Ident[1] == "attributes"
+		}
+		return false
+	}
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd)
+				}
+			}
+		case *parse.CommandNode:
+			if len(n.Args) >= 3 && n.Args[0].String() == "index" && isAttributes(n.Args[1]) {
+				if name, ok := n.Args[2].(*parse.StringNode); ok {
+					seen[name.Text] = true
+				}
+			}
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if len(n.Ident) >= 2 && n.Ident[0] == "attributes" {
+				seen[n.Ident[1]] = true
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) >= 3 && n.Ident[0] == "$" && n.Ident[1] == "attributes" {
+				seen[n.Ident[2]] = true
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	for _, t := range templates {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// derivedTranslator computes results from the entry in the scrape context.
+type derivedTranslator interface {
+	derive(ctx *scrapeContext) ([]*translationResult, error)
+}
+
+func (c *celTranslator) derive(ctx *scrapeContext) ([]*translationResult, error) {
+	vars, err := celVariables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.evaluate(vars)
+	if err != nil {
+		return nil, err
+	}
+	return []*translationResult{result}, nil
+}
+
+type derivedTemplate struct {
+	template *template.Template
+}
+
+func (d *derivedTemplate) derive(ctx *scrapeContext) ([]*translationResult, error) {
+	attributes := make(map[string][]string)
+	for _, attribute := range ctx.entry.Attributes {
+		attributes[attribute.Name] = attribute.Values
+	}
+	return do_the_translation_thing(d.template, map[string]interface{}{"attributes": attributes, "dn": ctx.entry.DN}, fmt.Sprintf("entry %s", ctx.entry.DN))
+}
+
+type DerivedMetric struct {
+	Desc       *prometheus.Desc
+	valueType  prometheus.ValueType
+	labels     []string
+	required   []string
+	optional   []string
+	translator derivedTranslator
+	scale      float64
+}
+
+func NewDerivedMetric(metric_name string, metric_type string, labels []string, constant_labels map[string]string, required []string, optional []string, translator derivedTranslator, scale float64, help string) *DerivedMetric {
+	if scale == 0 {
+		scale = 1
+	}
+	value_type := prometheus.GaugeValue
+	if metric_type == "counter" {
+		value_type = prometheus.CounterValue
+	}
+	return &DerivedMetric{
+		valueType:  value_type,
+		labels:     labels,
+		required:   required,
+		optional:   optional,
+		translator: translator,
+		scale:      scale,
+		Desc: prometheus.NewDesc(
+			metric_name,
+			help,
+			labels,
+			prometheus.Labels(constant_labels),
+		),
+	}
+}
+
+func (d *DerivedMetric) Collect(ctx *scrapeContext, entries []*ldap.Entry) ([]prometheus.Metric, error) {
+	var metrics []prometheus.Metric
+	for _, entry := range entries {
+		if !d.applies(entry) {
+			continue
+		}
+		entry_labels := ctx.entryLabels[entry]
+		entry_ctx := *ctx
+		entry_ctx.entry = entry
+		results, err := d.translator.derive(&entry_ctx)
+		if err != nil {
+			return nil, fmt.Errorf("derived metric %s failed for %s: %s", d.Desc, entry.DN, err)
+		}
+		for _, result := range results {
+			labels, err := buildOrderedLabels(d.labels, result.Labels, entry_labels)
+			if err != nil {
+				return nil, err
+			}
+			metric, err := prometheus.NewConstMetric(d.Desc, d.valueType, result.Value*d.scale, labels...)
+			if err != nil {
+				return nil, fmt.Errorf("Failed creating metric %s: %s", d.Desc, err)
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics, nil
+}
+
+// applies reports whether the entry has every attribute the metric requires.
+func (d *DerivedMetric) applies(entry *ldap.Entry) bool {
+	for _, attr := range d.required {
+		if len(entry.GetAttributeValues(attr)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *DerivedMetric) GetDesc() *prometheus.Desc {
+	return d.Desc
+}
+
+func (d *DerivedMetric) GetAttributes() []string {
+	return append(append([]string{}, d.required...), d.optional...)
+}
@@ -3,7 +3,10 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -15,20 +18,38 @@ import (
 
 const namespace = "ldap"
 
+// scrapeContext holds state shared by everything parsed during a single scrape.
+type scrapeContext struct {
+	// the reference time for age metrics; the server's clock if it could be determined.
+	now time.Time
+	// the entry whose attributes are being parsed.
+	entry *ldap.Entry
+	// the labels of each entry of the source being scraped.
+	entryLabels map[*ldap.Entry]map[string]string
+	// the source's join index, if it has a join.
+	joined map[string]map[string]string
+	// labels every metric of the source carries, such as the search base.
+	sourceLabels map[string]string
+	// the connection the source is scraped over.
+	conn *ldap.Conn
+}
+
 type MetricAttribute interface {
-	Parse(map[string]string, *ldap.EntryAttribute) ([]prometheus.Metric, error)
+	Parse(*scrapeContext, map[string]string, *ldap.EntryAttribute) ([]prometheus.Metric, error)
 	GetDesc() *prometheus.Desc
 }
 
 type CounterMetricAttribute struct {
 	Desc       *prometheus.Desc
 	labels     []string
-	translator *template.Template
+	translator valueTranslator
+	converter  *valueConverter
 }
 
-func NewCounterMetricAttribute(metric_name string, labels []string, constant_labels map[string]string, translator *template.Template, help string) *CounterMetricAttribute {
+func NewCounterMetricAttribute(metric_name string, labels []string, constant_labels map[string]string, translator valueTranslator, converter *valueConverter, help string) *CounterMetricAttribute {
 	return &CounterMetricAttribute{
 		translator: translator,
+		converter:  converter,
 		labels:     labels,
 		Desc: prometheus.NewDesc(
 			metric_name,
@@ -46,19 +67,100 @@ type translationResult struct {
 	X map[string]interface{} `yaml:",inline"`
 }
 
-func do_the_translation_thing(t *template.Template, values []string) ([]*translationResult, error) {
+// valueTranslator turns the values of an attribute into labeled results.
+type valueTranslator interface {
+	translate(ctx *scrapeContext, values []string) ([]*translationResult, error)
+}
+
+// templateTranslator is a config supplied translator template.
+type templateTranslator struct {
+	template *template.Template
+}
+
+func (t *templateTranslator) translate(ctx *scrapeContext, values []string) ([]*translationResult, error) {
+	return do_the_translation_thing(t.template, map[string]interface{}{"values": values, "value": values[0]}, fmt.Sprintf("value %s", values))
+}
+
+// emitTranslationResult converts the arguments of a translator's emit call into a result.
+func emitTranslationResult(value interface{}, labels ...map[string]interface{}) (*translationResult, error) {
+	result := &translationResult{Labels: make(map[string]string)}
+	switch v := value.(type) {
+	case float64:
+		result.Value = v
+	case float32:
+		result.Value = float64(v)
+	case int:
+		result.Value = float64(v)
+	case int64:
+		result.Value = float64(v)
+	case int32:
+		result.Value = float64(v)
+	case uint:
+		result.Value = float64(v)
+	case uint64:
+		result.Value = float64(v)
+	case uint32:
+		result.Value = float64(v)
+	case bool:
+		if v {
+			result.Value = 1
+		}
+	case string:
+		x, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("emit was given a non numeric value %q", v)
+		}
+		result.Value = x
+	default:
+		return nil, fmt.Errorf("emit was given a value of unsupported type %T: %v", value, value)
+	}
+	for _, l := range labels {
+		for k, v := range l {
+			result.Labels[k] = fmt.Sprint(v)
+		}
+	}
+	return result, nil
+}
+
+// do_the_translation_thing runs a translator.  Translators either call emit for each
+// result- which needs no further parsing and can't be corrupted by the LDAP values- or
+// render yaml which is then parsed.  what describes the input for error messages.
+func do_the_translation_thing(t *template.Template, data map[string]interface{}, what string) ([]*translationResult, error) {
+	// emit has to be bound per execution, thus work on a copy.
+	t, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	var emitted []*translationResult
+	t.Funcs(template.FuncMap{
+		"emit": func(value interface{}, labels ...map[string]interface{}) (string, error) {
+			result, err := emitTranslationResult(value, labels...)
+			if err != nil {
+				return "", err
+			}
+			emitted = append(emitted, result)
+			return "", nil
+		},
+	})
+
 	var buffer bytes.Buffer
-	if err := t.Option("missingkey=error").Execute(&buffer, map[string]interface{}{"values": values, "value": values[0]}); err != nil {
-		return nil, fmt.Errorf("failed parsing for value %s: error was %s", values, err)
+	if err := t.Option("missingkey=error").Execute(&buffer, data); err != nil {
+		return nil, fmt.Errorf("failed parsing for %s: error was %s", what, err)
+	}
+	if emitted != nil {
+		if len(strings.TrimSpace(buffer.String())) != 0 {
+			return nil, fmt.Errorf("failed parsing %s: translator both emitted results and rendered output:\n%s", what, buffer.String())
+		}
+		return emitted, nil
 	}
 	var results [](*translationResult)
 	// yay, got back something that is hopefully yaml
 	if err := yaml.Unmarshal([]byte(buffer.String()), &results); err != nil {
-		return nil, fmt.Errorf("failed parsing value %s due to %s; intermediate yaml was:\n%s", values, err, buffer.String())
+		return nil, fmt.Errorf("failed parsing %s due to %s; intermediate yaml was:\n%s", what, err, buffer.String())
 	}
 	for idx, result := range results {
 		if err := checkOverflow(result.X, "interpretting ldap->yaml translation"); err != nil {
-			return nil, fmt.Errorf("failed parsing value %s: had unknown field in position %d: %s", values, idx, err)
+			return nil, fmt.Errorf("failed parsing %s: had unknown field in position %d: %s", what, idx, err)
 		}
 	}
 	return results, nil
@@ -82,12 +184,12 @@ func buildOrderedLabels(desc_labels []string, label_sources ...map[string]string
 	return results, nil
 }
 
-func (c *CounterMetricAttribute) Parse(extra_labels map[string]string, entry *ldap.EntryAttribute) ([]prometheus.Metric, error) {
+func (c *CounterMetricAttribute) Parse(ctx *scrapeContext, extra_labels map[string]string, entry *ldap.EntryAttribute) ([]prometheus.Metric, error) {
 	if c.translator == nil {
 		if len(entry.Values) != 1 {
 			return nil, fmt.Errorf("Attribute %s resulted in %d matches, but no translator was defined to convert this into labeled counts", entry.Name, len(entry.Values))
 		}
-		x, err := strconv.ParseUint(entry.Values[0], 10, 64)
+		x, err := c.converter.convert(ctx, entry.Values[0])
 		if err != nil {
 			return nil, err
 		}
@@ -95,13 +197,13 @@ func (c *CounterMetricAttribute) Parse(extra_labels map[string]string, entry *ld
 		if err != nil {
 			return nil, err
 		}
-		metric, err := prometheus.NewConstMetric(c.Desc, prometheus.CounterValue, float64(x), labels...)
+		metric, err := prometheus.NewConstMetric(c.Desc, prometheus.CounterValue, x, labels...)
 		if err != nil {
 			return nil, err
 		}
 		return []prometheus.Metric{metric}, nil
 	}
-	values, err := do_the_translation_thing(c.translator, entry.Values)
+	values, err := c.translator.translate(ctx, entry.Values)
 	if err != nil {
 		return nil, err
 	}
@@ -111,7 +213,7 @@ func (c *CounterMetricAttribute) Parse(extra_labels map[string]string, entry *ld
 		if err != nil {
 			return nil, err
 		}
-		metric, err := prometheus.NewConstMetric(c.Desc, prometheus.CounterValue, value.Value, labels...)
+		metric, err := prometheus.NewConstMetric(c.Desc, prometheus.CounterValue, c.converter.adjust(ctx, value.Value), labels...)
 		if err != nil {
 			return nil, err
 		}
@@ -127,12 +229,14 @@ func (c *CounterMetricAttribute) GetDesc() *prometheus.Desc {
 type GaugeMetricAttribute struct {
 	Desc       *prometheus.Desc
 	labels     []string
-	translator *template.Template
+	translator valueTranslator
+	converter  *valueConverter
 }
 
-func NewGaugeMetricAttribute(metric_name string, labels []string, constant_labels map[string]string, translator *template.Template, help string) *GaugeMetricAttribute {
+func NewGaugeMetricAttribute(metric_name string, labels []string, constant_labels map[string]string, translator valueTranslator, converter *valueConverter, help string) *GaugeMetricAttribute {
 	return &GaugeMetricAttribute{
 		translator: translator,
+		converter:  converter,
 		labels:     labels,
 		Desc: prometheus.NewDesc(
 			metric_name,
@@ -143,13 +247,13 @@ func NewGaugeMetricAttribute(metric_name string, labels []string, constant_label
 	}
 }
 
-func (g *GaugeMetricAttribute) Parse(extra_labels map[string]string, entry *ldap.EntryAttribute) ([]prometheus.Metric, error) {
+func (g *GaugeMetricAttribute) Parse(ctx *scrapeContext, extra_labels map[string]string, entry *ldap.EntryAttribute) ([]prometheus.Metric, error) {
 	var metrics []prometheus.Metric
 	if g.translator == nil {
 		if len(entry.Values) != 1 {
 			return nil, fmt.Errorf("Attribute %s resulted in %d matches, but no translator was defined to convert this into labeled counts", entry.Name, len(entry.Values))
 		}
-		x, err := strconv.ParseFloat(entry.Values[0], 64)
+		x, err := g.converter.convert(ctx, entry.Values[0])
 		if err != nil {
 			return nil, err
 		}
@@ -157,13 +261,13 @@ func (g *GaugeMetricAttribute) Parse(extra_labels map[string]string, entry *ldap
 		if err != nil {
 			return nil, err
 		}
-		metric, err := prometheus.NewConstMetric(g.Desc, prometheus.CounterValue, float64(x), labels...)
+		metric, err := prometheus.NewConstMetric(g.Desc, prometheus.GaugeValue, x, labels...)
 		if err != nil {
 			return nil, fmt.Errorf("Failed creating metric %s: %s", g.Desc, err)
 		}
 		return []prometheus.Metric{metric}, nil
 	}
-	values, err := do_the_translation_thing(g.translator, entry.Values)
+	values, err := g.translator.translate(ctx, entry.Values)
 	if err != nil {
 		return nil, err
 	}
@@ -172,7 +276,7 @@ func (g *GaugeMetricAttribute) Parse(extra_labels map[string]string, entry *ldap
 		if err != nil {
 			return nil, err
 		}
-		metric, err := prometheus.NewConstMetric(g.Desc, prometheus.GaugeValue, value.Value, labels...)
+		metric, err := prometheus.NewConstMetric(g.Desc, prometheus.GaugeValue, g.converter.adjust(ctx, value.Value), labels...)
 		if err != nil {
 			return nil, err
 		}
@@ -185,13 +289,74 @@ func (g *GaugeMetricAttribute) GetDesc() *prometheus.Desc {
 	return g.Desc
 }
 
+// patternMetricAttribute exports every attribute whose name matches pattern.  Either all
+// matches are folded into a single metric with the attribute name as a label, or a metric
+// is created per attribute name on first sight.
+type patternMetricAttribute struct {
+	pattern        *regexp.Regexp
+	attributeLabel string
+	metric         MetricAttribute
+	factory        func(attribute string) (MetricAttribute, error)
+
+	lock         sync.Mutex
+	perAttribute map[string]MetricAttribute
+}
+
+// lookup returns the MetricAttribute to use for the given attribute name, and the labels to
+// add for it.  If the name doesn't match, a nil MetricAttribute is returned.
+func (p *patternMetricAttribute) lookup(attribute string) (MetricAttribute, map[string]string, error) {
+	matches := p.pattern.FindStringSubmatch(attribute)
+	if matches == nil {
+		return nil, nil, nil
+	}
+	if p.metric != nil {
+		label := attribute
+		if len(matches) > 1 {
+			label = matches[1]
+		}
+		return p.metric, map[string]string{p.attributeLabel: label}, nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if metric, ok := p.perAttribute[attribute]; ok {
+		return metric, nil, nil
+	}
+	metric, err := p.factory(attribute)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.perAttribute == nil {
+		p.perAttribute = make(map[string]MetricAttribute)
+	}
+	p.perAttribute[attribute] = metric
+	return metric, nil, nil
+}
+
 type MetricsSource struct {
-	SearchRequest    *ldap.SearchRequest
-	MetricAttributes map[string]MetricAttribute
-	LabelAttributes  map[string]string
+	Name              string
+	SearchRequest     *ldap.SearchRequest
+	MetricAttributes  map[string][]MetricAttribute
+	PatternAttributes []*patternMetricAttribute
+	SourceMetrics     []SourceMetric
+	LabelAttributes   map[string]string
+	// if any metrics are ages, the server's clock is looked up during the scrape.
+	NeedsReferenceTime bool
+	Join               *sourceJoin
+	// if set, the search is run beneath each of these rather than SearchRequest's base.
+	Bases          []string
+	NamingContexts bool
+	BaseLabel      string
+	// if set, the source is scraped over its own connection bound as this identity.
+	Bind *bindIdentity
+
+	// attributes only requested for SourceMetrics, joins, URLs, or cel expressions.
+	sourceAttributes map[string]struct{}
+	// the search base and filter if rendered per scrape.
+	templates searchTemplates
 }
 
-func NewMetricsSource(searchDN *string, filter *string, scope int, deref int, metric_attributes map[string]MetricAttribute, label_attributes map[string]string) *MetricsSource {
+func NewMetricsSource(searchDN *string, filter *string, scope int, deref int, metric_attributes map[string][]MetricAttribute, pattern_attributes []*patternMetricAttribute, source_metrics []SourceMetric, label_attributes map[string]string) *MetricsSource {
 	var attrs []string
 	for attr := range metric_attributes {
 		attrs = append(attrs, attr)
@@ -199,6 +364,30 @@ func NewMetricsSource(searchDN *string, filter *string, scope int, deref int, me
 	for attr, _ := range label_attributes {
 		attrs = append(attrs, attr)
 	}
+	source_attributes := make(map[string]struct{})
+	for _, metric := range source_metrics {
+		for _, attr := range metric.GetAttributes() {
+			if _, ok := metric_attributes[attr]; ok {
+				continue
+			}
+			if _, ok := label_attributes[attr]; ok {
+				continue
+			}
+			if _, ok := source_attributes[attr]; !ok {
+				source_attributes[attr] = struct{}{}
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+	if len(pattern_attributes) != 0 {
+		// we can't know which attributes will match, so ask for all of them.
+		attrs = append(attrs, "*")
+	}
+	if len(attrs) == 0 {
+		// an empty list means all attributes; 1.1 asks for none (RFC 4511 4.5.1.8), which is all
+		// that's needed for counting entries.
+		attrs = append(attrs, "1.1")
+	}
 	if filter == nil {
 		s := "(objectClass=*)"
 		filter = &s
@@ -211,13 +400,86 @@ func NewMetricsSource(searchDN *string, filter *string, scope int, deref int, me
 		nil,
 	)
 	m := MetricsSource{
-		SearchRequest:    search,
-		MetricAttributes: metric_attributes,
-		LabelAttributes:  label_attributes,
+		SearchRequest:     search,
+		MetricAttributes:  metric_attributes,
+		PatternAttributes: pattern_attributes,
+		SourceMetrics:     source_metrics,
+		LabelAttributes:   label_attributes,
+		sourceAttributes:  source_attributes,
 	}
 	return &m
 }
 
+func (m *MetricsSource) addJoin(join *sourceJoin) {
+	m.Join = join
+	m.addAttributes(join.primaryAttributes()...)
+}
+
+// addControl sends a request control with the source's search; if joins is set, with the
+// join's search too.
+func (m *MetricsSource) addControl(control ldap.Control, joins bool) {
+	m.SearchRequest.Controls = append(m.SearchRequest.Controls, control)
+	if joins && m.Join != nil {
+		m.Join.SearchRequest.Controls = append(m.Join.SearchRequest.Controls, control)
+	}
+}
+
+// addAttributes requests additional attributes that aren't otherwise exported.
+func (m *MetricsSource) addAttributes(attrs ...string) {
+	for _, attr := range attrs {
+		if _, ok := m.MetricAttributes[attr]; ok {
+			continue
+		}
+		if _, ok := m.LabelAttributes[attr]; ok {
+			continue
+		}
+		if _, ok := m.sourceAttributes[attr]; ok {
+			continue
+		}
+		m.sourceAttributes[attr] = struct{}{}
+		if len(m.SearchRequest.Attributes) == 1 && m.SearchRequest.Attributes[0] == "1.1" {
+			m.SearchRequest.Attributes = nil
+		}
+		m.SearchRequest.Attributes = append(m.SearchRequest.Attributes, attr)
+	}
+}
+
+// namingContextsPlaceholder stands in for the base of naming_contexts sources' URLs, since
+// the bases aren't known until the rootDSE is read.
+const namingContextsPlaceholder = "<namingContext>"
+
+// URLs renders the source's searches as RFC 4516 LDAP URLs, one per base.
+func (m *MetricsSource) URLs() []string {
+	bases := m.Bases
+	if m.NamingContexts {
+		bases = []string{namingContextsPlaceholder}
+	} else if len(bases) == 0 {
+		bases = []string{m.SearchRequest.BaseDN}
+	}
+	var urls []string
+	for _, base := range bases {
+		u := &ldapURL{
+			DN:         base,
+			Attributes: m.SearchRequest.Attributes,
+			Scope:      m.SearchRequest.Scope,
+			Filter:     m.SearchRequest.Filter,
+		}
+		urls = append(urls, u.String())
+	}
+	return urls
+}
+
+// hasAssertions is whether any of the source's metrics are assertions, which treat the base
+// not existing as the search finding nothing.
+func (m *MetricsSource) hasAssertions() bool {
+	for _, metric := range m.SourceMetrics {
+		if _, ok := metric.(*AssertionMetric); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *MetricsSource) String() string {
 	return fmt.Sprintf("search='%v', filter: '%v'", m.SearchRequest.BaseDN, m.SearchRequest.Filter)
 }
@@ -230,11 +492,18 @@ type Exporter struct {
 
 	conn           *ldap.Conn
 	metricsSources []*MetricsSource
+
+	// dial opens the connections of sources with their own bind identity.
+	dial      func() (*ldap.Conn, error)
+	boundLock sync.Mutex
+	bound     map[bindIdentity]*ldap.Conn
 }
 
-func NewExporter(conn *ldap.Conn, sources []*MetricsSource) *Exporter {
+func NewExporter(conn *ldap.Conn, dial func() (*ldap.Conn, error), sources []*MetricsSource) *Exporter {
 	return &Exporter{
 		conn:           conn,
+		dial:           dial,
+		bound:          make(map[bindIdentity]*ldap.Conn),
 		metricsSources: sources,
 		duration: prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
@@ -266,49 +535,191 @@ func NewExporter(conn *ldap.Conn, sources []*MetricsSource) *Exporter {
 
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 	log.Debug("describing metrics")
+	// the exporter's own metrics ensure there's always a descriptor, even if every metric is a
+	// per attribute pattern.
+	ch <- e.duration.Desc()
+	ch <- e.totalScrapes.Desc()
+	ch <- e.totalErrors.Desc()
+	ch <- e.scrapeError.Desc()
 	for _, query := range e.metricsSources {
-		for _, attr := range query.MetricAttributes {
-			ch <- attr.GetDesc()
+		for _, attrs := range query.MetricAttributes {
+			for _, attr := range attrs {
+				ch <- attr.GetDesc()
+			}
+		}
+		for _, metric := range query.SourceMetrics {
+			if multi, ok := metric.(interface{ GetDescs() []*prometheus.Desc }); ok {
+				for _, desc := range multi.GetDescs() {
+					ch <- desc
+				}
+				continue
+			}
+			ch <- metric.GetDesc()
+		}
+		// per attribute pattern metrics are only known once scraped, so they aren't described
+		// and the registry can't check them for consistency.
+		for _, pattern := range query.PatternAttributes {
+			if pattern.metric != nil {
+				ch <- pattern.metric.GetDesc()
+			}
 		}
 	}
 }
 
-func (m *MetricsSource) scrapeMetrics(result *ldap.SearchResult, ch chan<- prometheus.Metric) error {
-	for _, e := range result.Entries {
-		labels := make(map[string]string)
-		// first collect all attributes that are labels
-		for _, attribute := range e.Attributes {
-			if remapped_label_name, ok := m.LabelAttributes[attribute.Name]; ok {
-				if len(attribute.Values) != 1 {
-					return fmt.Errorf("attribute %s is a label type but has multiple values: %s", attribute.Name, attribute.Values)
-				}
-				labels[remapped_label_name] = attribute.Values[0]
+// entryLabels builds the labels every metric of the entry carries.
+func (m *MetricsSource) entryLabels(ctx *scrapeContext, e *ldap.Entry) (map[string]string, error) {
+	labels := make(map[string]string)
+	for label, value := range ctx.sourceLabels {
+		labels[label] = value
+	}
+	// first collect all attributes that are labels
+	for _, attribute := range e.Attributes {
+		if remapped_label_name, ok := m.LabelAttributes[attribute.Name]; ok {
+			if len(attribute.Values) != 1 {
+				return nil, fmt.Errorf("attribute %s is a label type but has multiple values: %s", attribute.Name, attribute.Values)
 			}
+			labels[remapped_label_name] = attribute.Values[0]
 		}
-		if len(labels) != len(m.LabelAttributes) {
-			// any metrics we generate will be rejected by prometheus due to label cardinality fail out.
-			return fmt.Errorf("required label attributes weren't found, thus metrics can't be exported for this query.  Attribute->label name mapping was %s, only built %s", m.LabelAttributes, labels)
+	}
+	if len(labels) != len(m.LabelAttributes)+len(ctx.sourceLabels) {
+		// any metrics we generate will be rejected by prometheus due to label cardinality fail out.
+		return nil, fmt.Errorf("required label attributes weren't found, thus metrics can't be exported for this query.  Attribute->label name mapping was %s, only built %s", m.LabelAttributes, labels)
+	}
+	if m.Join != nil {
+		joined, err := m.Join.lookup(ctx.joined, e)
+		if err != nil {
+			return nil, err
+		}
+		for label, value := range joined {
+			labels[label] = value
+		}
+	}
+	return labels, nil
+}
+
+func (m *MetricsSource) scrapeMetrics(ctx *scrapeContext, result *ldap.SearchResult, ch chan<- prometheus.Metric) error {
+	source_ctx := *ctx
+	source_ctx.entryLabels = make(map[*ldap.Entry]map[string]string, len(result.Entries))
+	for _, e := range result.Entries {
+		labels, err := m.entryLabels(ctx, e)
+		if err != nil {
+			return err
 		}
+		source_ctx.entryLabels[e] = labels
+	}
+	for _, e := range result.Entries {
+		entry_ctx := source_ctx
+		entry_ctx.entry = e
+		labels := source_ctx.entryLabels[e]
 		for _, attribute := range e.Attributes {
-			metricVec, ok := m.MetricAttributes[attribute.Name]
+			metricVecs, ok := m.MetricAttributes[attribute.Name]
 			if !ok {
-				if _, ok := m.LabelAttributes[attribute.Name]; !ok {
+				if _, ok := m.LabelAttributes[attribute.Name]; ok {
+					continue
+				}
+				if _, ok := m.sourceAttributes[attribute.Name]; ok {
+					continue
+				}
+				if len(m.PatternAttributes) == 0 {
 					return fmt.Errorf("server sent us an attribute we do not recognize (%s); this is likely a bug in the exporter", attribute.Name)
 				}
+				if err := m.scrapePatternMetrics(&entry_ctx, labels, attribute, ch); err != nil {
+					return err
+				}
 				continue
 			}
-			metrics, err := metricVec.Parse(labels, attribute)
-			if err != nil {
-				return fmt.Errorf("while scraping %v: %s", m, err)
+			for _, metricVec := range metricVecs {
+				metrics, err := metricVec.Parse(&entry_ctx, labels, attribute)
+				if err != nil {
+					return fmt.Errorf("while scraping %v: %s", m, err)
+				}
+				for _, metric := range metrics {
+					ch <- metric
+				}
 			}
-			for _, metric := range metrics {
-				ch <- metric
+		}
+	}
+	for _, sourceMetric := range m.SourceMetrics {
+		metrics, err := sourceMetric.Collect(&source_ctx, result.Entries)
+		if err != nil {
+			return fmt.Errorf("while scraping %v: %s", m, err)
+		}
+		for _, metric := range metrics {
+			ch <- metric
+		}
+	}
+	return nil
+}
+
+// scrapePatternMetrics handles attributes that weren't explicitly configured; anything not matching
+// a pattern is ignored since the server is returning every attribute of the entry.
+func (m *MetricsSource) scrapePatternMetrics(ctx *scrapeContext, labels map[string]string, attribute *ldap.EntryAttribute, ch chan<- prometheus.Metric) error {
+	for _, pattern := range m.PatternAttributes {
+		metricVec, pattern_labels, err := pattern.lookup(attribute.Name)
+		if err != nil {
+			return fmt.Errorf("while scraping %v: %s", m, err)
+		}
+		if metricVec == nil {
+			continue
+		}
+		if len(pattern_labels) != 0 {
+			for k, v := range labels {
+				pattern_labels[k] = v
 			}
+		} else {
+			pattern_labels = labels
+		}
+		metrics, err := metricVec.Parse(ctx, pattern_labels, attribute)
+		if err != nil {
+			return fmt.Errorf("while scraping %v: %s", m, err)
+		}
+		for _, metric := range metrics {
+			ch <- metric
 		}
 	}
 	return nil
 }
 
+// where servers publish their clock, tried in order; the rootDSE's currentTime is
+// Active Directory, cn=monitor is 389, and cn=Current,cn=Time,cn=Monitor is OpenLDAP.
+var referenceTimeSources = []struct {
+	base      string
+	attribute string
+}{
+	{"", "currentTime"},
+	{"cn=monitor", "currentTime"},
+	{"cn=Current,cn=Time,cn=Monitor", "monitorTimestamp"},
+}
+
+// referenceTime returns the server's notion of now so ages aren't skewed by clock drift
+// between the exporter and the server.  If the server doesn't expose it, the local clock is used.
+func (e *Exporter) referenceTime() time.Time {
+	for _, source := range referenceTimeSources {
+		result, err := e.conn.Search(ldap.NewSearchRequest(
+			source.base,
+			ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+			"(objectClass=*)",
+			[]string{source.attribute},
+			nil,
+		))
+		if err != nil || len(result.Entries) != 1 {
+			continue
+		}
+		value := result.Entries[0].GetAttributeValue(source.attribute)
+		if value == "" {
+			continue
+		}
+		now, err := parseGeneralizedTime(value)
+		if err != nil {
+			log.Debugf("ignoring server time from %s in '%s': %s", source.attribute, source.base, err)
+			continue
+		}
+		return now
+	}
+	log.Debug("server time couldn't be determined; using the local clock for age metrics")
+	return time.Now()
+}
+
 func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 	e.totalScrapes.Inc()
 
@@ -319,20 +730,159 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 		e.totalErrors.Add(failures)
 	}(time.Now())
 
+	ctx := &scrapeContext{now: time.Now()}
 	for _, source := range e.metricsSources {
-		result, err := e.conn.Search(source.SearchRequest)
+		if source.NeedsReferenceTime {
+			ctx.now = e.referenceTime()
+			break
+		}
+	}
+
+	var naming_contexts []string
+	for _, source := range e.metricsSources {
+		conn, err := e.connection(source.Bind)
 		if err != nil {
 			log.Errorf("failed scraping for %v; Error was: %s", source, err)
 			failures += 1
 			continue
 		}
-		err = source.scrapeMetrics(result, ch)
-		if err != nil {
-			log.Errorf("failed scraping for %v; Error was: %s", source, err)
-			failures += 1
+		conn_ctx := *ctx
+		conn_ctx.conn = conn
+		source_ctx := &conn_ctx
+		if source.Join != nil {
+			index, err := e.join(conn, ctx, source.Join)
+			if err != nil {
+				log.Errorf("failed the join for %v; Error was: %s", source, err)
+				e.checkConnection(source.Bind, err)
+				failures += 1
+				continue
+			}
+			join_ctx := conn_ctx
+			join_ctx.joined = index
+			source_ctx = &join_ctx
+		}
+		bases := source.Bases
+		if source.NamingContexts {
+			if naming_contexts == nil {
+				var err error
+				if naming_contexts, err = e.namingContexts(); err != nil {
+					log.Errorf("failed scraping for %v; Error was: %s", source, err)
+					failures += 1
+					continue
+				}
+			}
+			bases = naming_contexts
+		} else if len(bases) == 0 {
+			bases = []string{source.SearchRequest.BaseDN}
+		}
+		for _, base := range bases {
+			if err := e.scrapeBase(conn, source_ctx, source, base, ch); err != nil {
+				log.Errorf("failed scraping for %v under '%s'; Error was: %s", source, base, err)
+				e.checkConnection(source.Bind, err)
+				failures += 1
+			}
 		}
 	}
+}
 
+// scrapeBase runs the source's search beneath a single base.
+func (e *Exporter) scrapeBase(conn *ldap.Conn, ctx *scrapeContext, source *MetricsSource, base string, ch chan<- prometheus.Metric) error {
+	request := *source.SearchRequest
+	request.BaseDN = base
+	rendered, err := source.templates.request(&request, ctx.now)
+	if err != nil {
+		return err
+	}
+	result, err := conn.Search(rendered)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) && source.hasAssertions() {
+		// asserting an entry exists shouldn't fail the scrape when it doesn't.
+		result, err = &ldap.SearchResult{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if source.BaseLabel != "" {
+		base_ctx := *ctx
+		base_ctx.sourceLabels = map[string]string{source.BaseLabel: rendered.BaseDN}
+		ctx = &base_ctx
+	}
+	return source.scrapeMetrics(ctx, result, ch)
+}
+
+func (e *Exporter) join(conn *ldap.Conn, ctx *scrapeContext, join *sourceJoin) (map[string]map[string]string, error) {
+	request, err := join.templates.request(join.SearchRequest, ctx.now)
+	if err != nil {
+		return nil, err
+	}
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, err
+	}
+	return join.index(result.Entries)
+}
+
+// connection returns the connection to scrape a source over; the shared one unless the
+// source binds as its own identity, in which case it's dialed and bound on first use.
+func (e *Exporter) connection(identity *bindIdentity) (*ldap.Conn, error) {
+	if identity == nil {
+		return e.conn, nil
+	}
+	e.boundLock.Lock()
+	defer e.boundLock.Unlock()
+	if conn, ok := e.bound[*identity]; ok {
+		return conn, nil
+	}
+	password, err := identity.password()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := e.dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed connecting for bind dn %s: %s", identity, err)
+	}
+	if err := conn.Bind(identity.dn, password); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed binding as %s: %s", identity, err)
+	}
+	log.Debugf("bound a connection as %s", identity)
+	e.bound[*identity] = conn
+	return conn, nil
+}
+
+// checkConnection drops a source's connection if err shows it's unusable, so the next
+// scrape redials rather than failing until restart.
+func (e *Exporter) checkConnection(identity *bindIdentity, err error) {
+	if identity == nil || !ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		return
+	}
+	e.boundLock.Lock()
+	defer e.boundLock.Unlock()
+	if conn, ok := e.bound[*identity]; ok {
+		conn.Close()
+		delete(e.bound, *identity)
+	}
+}
+
+// namingContexts returns the suffixes the rootDSE advertises.
+func (e *Exporter) namingContexts() ([]string, error) {
+	result, err := e.conn.Search(ldap.NewSearchRequest(
+		"",
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)",
+		[]string{"namingContexts"},
+		nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("rootDSE search returned %d entries", len(result.Entries))
+	}
+	contexts := result.Entries[0].GetAttributeValues("namingContexts")
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("the rootDSE lists no namingContexts")
+	}
+	return contexts, nil
 }
 
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPatternAttributeLabel(t *testing.T) {
+	sources, err := LoadConfig(`
+- name: ops
+  search: cn=monitor
+  scope: base
+  attributes:
+    patterns:
+      ops$:
+        type: counter
+        metric_name: ops_total
+        help: Operations by type.
+        attribute_label: op
+      ^(.*)entries$:
+        type: gauge
+        metric_name: entries
+        help: Entries by kind.
+        attribute_label: kind
+`)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %s", err)
+	}
+	patterns := make(map[string]*patternMetricAttribute)
+	for _, pattern := range sources[0].PatternAttributes {
+		patterns[pattern.pattern.String()] = pattern
+	}
+	for _, tc := range []struct {
+		pattern   string
+		attribute string
+		label     string
+		value     string
+	}{
+		// without a capture group the whole name is the label, not just the match.
+		{"ops$", "readops", "op", "readops"},
+		{"ops$", "writeops", "op", "writeops"},
+		{"^(.*)entries$", "currententries", "kind", "current"},
+	} {
+		metric, labels, err := patterns[tc.pattern].lookup(tc.attribute)
+		if err != nil {
+			t.Errorf("lookup(%q) of %s failed: %s", tc.attribute, tc.pattern, err)
+		} else if metric == nil {
+			t.Errorf("lookup(%q) of %s didn't match", tc.attribute, tc.pattern)
+		} else if labels[tc.label] != tc.value {
+			t.Errorf("lookup(%q) of %s = %v, expected %s=%q", tc.attribute, tc.pattern, labels, tc.label, tc.value)
+		}
+	}
+	if metric, _, _ := patterns["ops$"].lookup("opsx"); metric != nil {
+		t.Errorf("lookup of a non matching attribute should return nil")
+	}
+}
+
+func TestDescribePatternsOnly(t *testing.T) {
+	sources, err := LoadConfig(`
+- name: ops
+  search: cn=monitor
+  scope: base
+  attributes:
+    patterns:
+      ops$:
+        type: counter
+        help: Operations of the type in the attribute name.
+`)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %s", err)
+	}
+	if err := prometheus.NewRegistry().Register(NewExporter(nil, nil, sources)); err != nil {
+		t.Errorf("registering a config of only per attribute patterns failed: %s", err)
+	}
+}
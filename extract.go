@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// extractConfig is a declarative alternative to translator templates; values are
+// either matched against a regex or split on a delimiter, and the resultant groups
+// are assigned to the value and labels.
+type extractConfig struct {
+	Regex string  `yaml:"regex"`
+	Split *string `yaml:"split"`
+	// the group holding the value; if unset, the value is always 1.
+	Value string `yaml:"value"`
+	// label name -> group.  For regexes this defaults to every named group but the value.
+	Labels      map[string]string `yaml:"labels"`
+	ValueFormat string            `yaml:"value_format"`
+
+	translator *extractTranslator
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (e *extractConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain extractConfig
+
+	if err := unmarshal((*plain)(e)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(e.X, "extract"); err != nil {
+		return err
+	}
+
+	t := &extractTranslator{valueGroup: -1, parse: parseNumber, labels: make(map[string]int)}
+	var groups func(string) (int, error)
+	if e.Regex != "" {
+		if e.Split != nil {
+			return fmt.Errorf("extract regex and split are mutually exclusive")
+		}
+		re, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return fmt.Errorf("extract regex is malformed: %s", err)
+		}
+		t.regex = re
+		groups = func(group string) (int, error) {
+			for idx, name := range re.SubexpNames() {
+				if idx > 0 && name == group {
+					return idx, nil
+				}
+			}
+			if idx, err := strconv.Atoi(group); err == nil && idx >= 0 && idx <= re.NumSubexp() {
+				return idx, nil
+			}
+			return 0, fmt.Errorf("extract regex has no group %s", group)
+		}
+		if len(e.Labels) == 0 {
+			e.Labels = make(map[string]string)
+			for _, name := range re.SubexpNames() {
+				if name != "" && name != e.Value {
+					e.Labels[name] = name
+				}
+			}
+		}
+	} else if e.Split != nil {
+		if *e.Split == "" {
+			return fmt.Errorf("extract split delimiter cannot be empty")
+		}
+		t.split = *e.Split
+		groups = func(group string) (int, error) {
+			idx, err := strconv.Atoi(group)
+			if err != nil || idx < 0 {
+				return 0, fmt.Errorf("extract split groups must be nonnegative field indexes, got %s", group)
+			}
+			return idx, nil
+		}
+	} else {
+		return fmt.Errorf("extract requires either regex or split")
+	}
+
+	if e.Value != "" {
+		idx, err := groups(e.Value)
+		if err != nil {
+			return err
+		}
+		t.valueGroup = idx
+	}
+	for label, group := range e.Labels {
+		if label == "" || len(strings.TrimSpace(label)) != len(label) {
+			return fmt.Errorf("extract label cannot have whitespace and must be nonempty: '%s'", label)
+		}
+		idx, err := groups(group)
+		if err != nil {
+			return err
+		}
+		t.labels[label] = idx
+	}
+	if e.ValueFormat != "" {
+		parser, err := lookupValueFormat(e.ValueFormat)
+		if err != nil {
+			return err
+		}
+		t.parse = parser
+	}
+	e.translator = t
+	return nil
+}
+
+func (e *extractConfig) labelNames() []string {
+	var names []string
+	for label := range e.Labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type extractTranslator struct {
+	regex      *regexp.Regexp
+	split      string
+	valueGroup int
+	labels     map[string]int
+	parse      valueParser
+}
+
+func (e *extractTranslator) translate(ctx *scrapeContext, values []string) ([]*translationResult, error) {
+	var results []*translationResult
+	for _, value := range values {
+		var groups []string
+		if e.regex != nil {
+			if groups = e.regex.FindStringSubmatch(value); groups == nil {
+				return nil, fmt.Errorf("value %q doesn't match extract regex %s", value, e.regex)
+			}
+		} else {
+			groups = strings.Split(value, e.split)
+		}
+		group := func(idx int) (string, error) {
+			if idx >= len(groups) {
+				return "", fmt.Errorf("value %q has no field %d when split on %q", value, idx, e.split)
+			}
+			return groups[idx], nil
+		}
+
+		result := &translationResult{Value: 1, Labels: make(map[string]string, len(e.labels))}
+		if e.valueGroup >= 0 {
+			raw, err := group(e.valueGroup)
+			if err != nil {
+				return nil, err
+			}
+			if result.Value, err = e.parse(raw); err != nil {
+				return nil, fmt.Errorf("failed parsing value %q: %s", value, err)
+			}
+		}
+		for label, idx := range e.labels {
+			v, err := group(idx)
+			if err != nil {
+				return nil, err
+			}
+			result.Labels[label] = v
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestExtract(t *testing.T) {
+	for _, tc := range []struct {
+		config   string
+		value    string
+		expected translationResult
+	}{
+		{
+			config:   `{regex: '^(?P<backend>\w+):(?P<count>\d+)$', value: count}`,
+			value:    "userRoot:42",
+			expected: translationResult{Value: 42, Labels: map[string]string{"backend": "userRoot"}},
+		},
+		{
+			config:   `{regex: '^(\w+)=(\d+)$', value: "2", labels: {name: "1"}}`,
+			value:    "conns=7",
+			expected: translationResult{Value: 7, Labels: map[string]string{"name": "conns"}},
+		},
+		{
+			config:   `{regex: '^(?P<state>on|off)$'}`,
+			value:    "on",
+			expected: translationResult{Value: 1, Labels: map[string]string{"state": "on"}},
+		},
+		{
+			config:   `{regex: '^(?P<name>\w+) (?P<size>\S+)$', value: size, value_format: size}`,
+			value:    "cache 2k",
+			expected: translationResult{Value: 2048, Labels: map[string]string{"name": "cache"}},
+		},
+		{
+			config:   `{split: ":", value: "2", labels: {host: "0", port: "1"}}`,
+			value:    "ldap.example.com:389:3",
+			expected: translationResult{Value: 3, Labels: map[string]string{"host": "ldap.example.com", "port": "389"}},
+		},
+		{
+			config:   `{split: " ", labels: {first: "0"}}`,
+			value:    "a b",
+			expected: translationResult{Value: 1, Labels: map[string]string{"first": "a"}},
+		},
+	} {
+		var config extractConfig
+		if err := yaml.Unmarshal([]byte(tc.config), &config); err != nil {
+			t.Errorf("extract %s failed: %s", tc.config, err)
+			continue
+		}
+		results, err := config.translator.translate(&scrapeContext{}, []string{tc.value})
+		if err != nil {
+			t.Errorf("extract %s of %q failed: %s", tc.config, tc.value, err)
+		} else if len(results) != 1 || !reflect.DeepEqual(*results[0], tc.expected) {
+			t.Errorf("extract %s of %q = %+v, expected %+v", tc.config, tc.value, results, tc.expected)
+		}
+	}
+	for _, config := range []string{
+		`{}`,
+		`{regex: '(', value: "1"}`,
+		`{regex: '(\d+)', split: ":"}`,
+		`{regex: '(\d+)', value: missing}`,
+		`{regex: '(\d+)', value: "2"}`,
+		`{split: ""}`,
+		`{split: ":", value: name}`,
+		`{split: ":", labels: {" x": "0"}}`,
+		`{regex: '(\d+)', value: "1", value_format: nope}`,
+	} {
+		var extract extractConfig
+		if err := yaml.Unmarshal([]byte(config), &extract); err == nil {
+			t.Errorf("extract %s should fail", config)
+		}
+	}
+	for config, value := range map[string]string{
+		`{regex: '^(?P<count>\d+)$', value: count}`: "x",
+		`{split: ":", value: "2"}`:                  "a:b",
+		`{split: ":", value: "1"}`:                  "a:b:c",
+	} {
+		var extract extractConfig
+		if err := yaml.Unmarshal([]byte(config), &extract); err != nil {
+			t.Errorf("extract %s failed: %s", config, err)
+		} else if _, err := extract.translator.translate(&scrapeContext{}, []string{value}); err == nil {
+			t.Errorf("extract %s of %q should fail", config, value)
+		}
+	}
+}
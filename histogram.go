@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/ldap.v2"
+)
+
+// histogramData accumulates observations for a single label set of a const histogram.
+type histogramData struct {
+	labels  []string
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+}
+
+func newHistogramData(buckets []float64, labels []string) *histogramData {
+	h := &histogramData{
+		labels:  labels,
+		buckets: make(map[float64]uint64, len(buckets)),
+	}
+	for _, upper_bound := range buckets {
+		h.buckets[upper_bound] = 0
+	}
+	return h
+}
+
+func (h *histogramData) observe(value float64) {
+	h.count++
+	h.sum += value
+	// const histograms want cumulative counts.
+	for upper_bound := range h.buckets {
+		if value <= upper_bound {
+			h.buckets[upper_bound]++
+		}
+	}
+}
+
+func (h *histogramData) metric(desc *prometheus.Desc) (prometheus.Metric, error) {
+	metric, err := prometheus.NewConstHistogram(desc, h.count, h.sum, h.buckets, h.labels...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating metric %s: %s", desc, err)
+	}
+	return metric, nil
+}
+
+// HistogramMetricAttribute builds a histogram per entry out of every value of a
+// multi-valued attribute.
+type HistogramMetricAttribute struct {
+	Desc       *prometheus.Desc
+	labels     []string
+	translator valueTranslator
+	converter  *valueConverter
+	buckets    []float64
+}
+
+func NewHistogramMetricAttribute(metric_name string, labels []string, constant_labels map[string]string, translator valueTranslator, converter *valueConverter, buckets []float64, help string) *HistogramMetricAttribute {
+	return &HistogramMetricAttribute{
+		translator: translator,
+		converter:  converter,
+		labels:     labels,
+		buckets:    buckets,
+		Desc: prometheus.NewDesc(
+			metric_name,
+			help,
+			labels,
+			prometheus.Labels(constant_labels),
+		),
+	}
+}
+
+func (h *HistogramMetricAttribute) Parse(ctx *scrapeContext, extra_labels map[string]string, entry *ldap.EntryAttribute) ([]prometheus.Metric, error) {
+	if h.translator == nil {
+		labels, err := buildOrderedLabels(h.labels, extra_labels)
+		if err != nil {
+			return nil, err
+		}
+		data := newHistogramData(h.buckets, labels)
+		for _, value := range entry.Values {
+			x, err := h.converter.convert(ctx, value)
+			if err != nil {
+				return nil, err
+			}
+			data.observe(x)
+		}
+		metric, err := data.metric(h.Desc)
+		if err != nil {
+			return nil, err
+		}
+		return []prometheus.Metric{metric}, nil
+	}
+	values, err := h.translator.translate(ctx, entry.Values)
+	if err != nil {
+		return nil, err
+	}
+	// translators may emit labels; each distinct label set is its own histogram.
+	var order []string
+	histograms := make(map[string]*histogramData)
+	for _, value := range values {
+		labels, err := buildOrderedLabels(h.labels, value.Labels, extra_labels)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.Join(labels, "\x00")
+		data, ok := histograms[key]
+		if !ok {
+			data = newHistogramData(h.buckets, labels)
+			histograms[key] = data
+			order = append(order, key)
+		}
+		data.observe(h.converter.adjust(ctx, value.Value))
+	}
+	var metrics []prometheus.Metric
+	for _, key := range order {
+		metric, err := histograms[key].metric(h.Desc)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+func (h *HistogramMetricAttribute) GetDesc() *prometheus.Desc {
+	return h.Desc
+}
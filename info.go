@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/ldap.v2"
+)
+
+// InfoMetricAttribute exports each value of an attribute as a label of a series that
+// is always 1.  The value label is the last of labels.
+type InfoMetricAttribute struct {
+	Desc   *prometheus.Desc
+	labels []string
+}
+
+func NewInfoMetricAttribute(metric_name string, labels []string, constant_labels map[string]string, help string) *InfoMetricAttribute {
+	return &InfoMetricAttribute{
+		labels: labels,
+		Desc: prometheus.NewDesc(
+			metric_name,
+			help,
+			labels,
+			prometheus.Labels(constant_labels),
+		),
+	}
+}
+
+func (i *InfoMetricAttribute) Parse(ctx *scrapeContext, extra_labels map[string]string, entry *ldap.EntryAttribute) ([]prometheus.Metric, error) {
+	labels, err := buildOrderedLabels(i.labels[:len(i.labels)-1], extra_labels)
+	if err != nil {
+		return nil, err
+	}
+	var metrics []prometheus.Metric
+	for _, value := range entry.Values {
+		metric, err := prometheus.NewConstMetric(i.Desc, prometheus.GaugeValue, 1, append(labels, value)...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed creating metric %s: %s", i.Desc, err)
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+func (i *InfoMetricAttribute) GetDesc() *prometheus.Desc {
+	return i.Desc
+}
+
+// StateSetMetricAttribute exports a series per declared state; the state(s) the attribute
+// currently holds are 1, everything else 0.  If it holds no declared state, all are 0.  The
+// state label is the last of labels.
+type StateSetMetricAttribute struct {
+	Desc   *prometheus.Desc
+	labels []string
+	states []string
+}
+
+func NewStateSetMetricAttribute(metric_name string, labels []string, constant_labels map[string]string, states []string, help string) *StateSetMetricAttribute {
+	return &StateSetMetricAttribute{
+		labels: labels,
+		states: states,
+		Desc: prometheus.NewDesc(
+			metric_name,
+			help,
+			labels,
+			prometheus.Labels(constant_labels),
+		),
+	}
+}
+
+func (s *StateSetMetricAttribute) Parse(ctx *scrapeContext, extra_labels map[string]string, entry *ldap.EntryAttribute) ([]prometheus.Metric, error) {
+	labels, err := buildOrderedLabels(s.labels[:len(s.labels)-1], extra_labels)
+	if err != nil {
+		return nil, err
+	}
+	matched := false
+	var metrics []prometheus.Metric
+	for _, state := range s.states {
+		value := float64(0)
+		for _, v := range entry.Values {
+			// most LDAP enumerations (TRUE/FALSE, on/off) are case insensitive.
+			if strings.EqualFold(state, v) {
+				value = 1
+				matched = true
+				break
+			}
+		}
+		metric, err := prometheus.NewConstMetric(s.Desc, prometheus.GaugeValue, value, append(labels, state)...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed creating metric %s: %s", s.Desc, err)
+		}
+		metrics = append(metrics, metric)
+	}
+	if !matched {
+		// every state is reported as 0 rather than failing the source; a state the server
+		// added shouldn't blank everything else.
+		log.Warnf("attribute %s has values %s, none of which are a known state: %s", entry.Name, entry.Values, s.states)
+	}
+	return metrics, nil
+}
+
+func (s *StateSetMetricAttribute) GetDesc() *prometheus.Desc {
+	return s.Desc
+}
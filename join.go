@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/ldap.v2"
+)
+
+const (
+	joinKeyDN       = "dn"
+	joinKeyParentDN = "parent_dn"
+)
+
+// joinConfig enriches a source's entries with labels from the entries of a second search.
+// The joined entries are indexed by key, and each primary entry's match is looked up in
+// that index; both are either dn, parent_dn, or an attribute name.  Primary entries without
+// a matching entry get empty labels.
+type joinConfig struct {
+	Search *dnString     `yaml:"search"`
+	Filter *filterString `yaml:"filter"`
+	// unlike sources, this defaults to subtree since joins are rarely against a single entry.
+	Scope *scopeChoice `yaml:"scope"`
+	Key   string       `yaml:"key"`
+	Match string       `yaml:"match"`
+	// joined attribute -> label name.
+	Labels map[string]string `yaml:"labels"`
+
+	join *sourceJoin
+
+	X map[string]interface{} `yaml:",inline"`
+}
+
+func (jc *joinConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain joinConfig
+
+	if err := unmarshal((*plain)(jc)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(jc.X, "join"); err != nil {
+		return err
+	}
+
+	if jc.Search == nil {
+		return fmt.Errorf("join search is either empty or undefined")
+	}
+	filter := "(objectClass=*)"
+	if jc.Filter != nil {
+		filter = string(*jc.Filter)
+	}
+	scope := ldap.ScopeWholeSubtree
+	if jc.Scope != nil {
+		scope = int(*jc.Scope)
+	}
+	if jc.Key == "" {
+		jc.Key = joinKeyDN
+	}
+	if jc.Match == "" {
+		return fmt.Errorf("join match must be defined; use dn, parent_dn, or an attribute name")
+	}
+	if len(jc.Labels) == 0 {
+		return fmt.Errorf("join must define at least one label")
+	}
+	for attr, label := range jc.Labels {
+		if label == "" || len(strings.TrimSpace(label)) != len(label) {
+			return fmt.Errorf("join label for attribute %s cannot have whitespace and must be nonempty: '%s'", attr, label)
+		}
+	}
+
+	var attrs []string
+	for attr := range jc.Labels {
+		attrs = append(attrs, attr)
+	}
+	if !isDNJoinKey(jc.Key) && !containsString(attrs, jc.Key) {
+		attrs = append(attrs, jc.Key)
+	}
+	sort.Strings(attrs)
+	templates, err := newSearchTemplates([]string{string(*jc.Search)}, filter)
+	if err != nil {
+		return fmt.Errorf("join %s", err)
+	}
+	jc.join = &sourceJoin{
+		SearchRequest: ldap.NewSearchRequest(
+			string(*jc.Search),
+			scope, ldap.NeverDerefAliases, 0, 0, false,
+			filter,
+			attrs,
+			nil,
+		),
+		templates: templates,
+		key:       jc.Key,
+		match:     jc.Match,
+		labels:    jc.Labels,
+	}
+	return nil
+}
+
+func isDNJoinKey(key string) bool {
+	return key == joinKeyDN || key == joinKeyParentDN
+}
+
+type sourceJoin struct {
+	SearchRequest *ldap.SearchRequest
+	templates     searchTemplates
+	key           string
+	match         string
+	labels        map[string]string
+}
+
+func (j *sourceJoin) labelNames() []string {
+	var names []string
+	for _, label := range j.labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// primaryAttributes are the attributes the primary search must request for matching.
+func (j *sourceJoin) primaryAttributes() []string {
+	if isDNJoinKey(j.match) {
+		return nil
+	}
+	return []string{j.match}
+}
+
+// joinKeys derives the normalized keys of an entry; attributes may have several.
+func joinKeys(kind string, entry *ldap.Entry) ([]string, error) {
+	switch kind {
+	case joinKeyDN, joinKeyParentDN:
+		parsed, err := ldap.ParseDN(entry.DN)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing dn %q: %s", entry.DN, err)
+		}
+		rdns := parsed.RDNs
+		if kind == joinKeyParentDN {
+			if len(rdns) == 0 {
+				return nil, nil
+			}
+			rdns = rdns[1:]
+		}
+		return []string{normalizeRDNs(rdns)}, nil
+	}
+	var keys []string
+	for _, value := range entry.GetAttributeValues(kind) {
+		keys = append(keys, normalizeJoinValue(value))
+	}
+	return keys, nil
+}
+
+// normalizeJoinValue allows DN valued attributes to match regardless of formatting; anything
+// else is compared case insensitively as most matching rules are.
+func normalizeJoinValue(value string) string {
+	if normalized, err := normalizeDN(value); err == nil && normalized != "" {
+		return normalized
+	}
+	return strings.ToLower(value)
+}
+
+// index maps the keys of the joined entries to the labels they provide.
+func (j *sourceJoin) index(entries []*ldap.Entry) (map[string]map[string]string, error) {
+	index := make(map[string]map[string]string)
+	for _, entry := range entries {
+		labels := make(map[string]string, len(j.labels))
+		for attr, label := range j.labels {
+			values := entry.GetAttributeValues(attr)
+			if len(values) > 1 {
+				return nil, fmt.Errorf("join attribute %s of %s is a label but has multiple values: %s", attr, entry.DN, values)
+			}
+			if len(values) == 1 {
+				labels[label] = values[0]
+			} else {
+				labels[label] = ""
+			}
+		}
+		keys, err := joinKeys(j.key, entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if _, ok := index[key]; ok {
+				return nil, fmt.Errorf("join key %s matched multiple entries, including %s", key, entry.DN)
+			}
+			index[key] = labels
+		}
+	}
+	return index, nil
+}
+
+// lookup returns the joined labels for a primary entry.
+func (j *sourceJoin) lookup(index map[string]map[string]string, entry *ldap.Entry) (map[string]string, error) {
+	keys, err := joinKeys(j.match, entry)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if labels, ok := index[key]; ok {
+			return labels, nil
+		}
+	}
+	labels := make(map[string]string, len(j.labels))
+	for _, label := range j.labels {
+		labels[label] = ""
+	}
+	return labels, nil
+}
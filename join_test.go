@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/ldap.v2"
+	"gopkg.in/yaml.v2"
+)
+
+func TestJoin(t *testing.T) {
+	backends := []*ldap.Entry{
+		ldap.NewEntry("cn=userRoot,cn=ldbm database,cn=plugins,cn=config", map[string][]string{"nsslapd-suffix": {"dc=example,dc=com"}}),
+		ldap.NewEntry("cn=ipaca,cn=ldbm database,cn=plugins,cn=config", map[string][]string{"nsslapd-suffix": {"o=ipaca"}}),
+	}
+	for _, tc := range []struct {
+		config   string
+		joined   []*ldap.Entry
+		dn       string
+		attrs    map[string][]string
+		expected map[string]string
+	}{
+		{
+			config:   `{search: cn=config, key: dn, match: parent_dn, labels: {nsslapd-suffix: suffix}}`,
+			joined:   backends,
+			dn:       "cn=monitor,cn=userRoot,cn=ldbm database,cn=plugins,cn=config",
+			expected: map[string]string{"suffix": "dc=example,dc=com"},
+		},
+		// DNs are compared after normalizing case and spacing.
+		{
+			config:   `{search: cn=config, match: parent_dn, labels: {nsslapd-suffix: suffix}}`,
+			joined:   backends,
+			dn:       "CN=monitor, CN=IPACA,cn=LDBM Database,cn=plugins,cn=config",
+			expected: map[string]string{"suffix": "o=ipaca"},
+		},
+		{
+			config: `{search: cn=config, key: parent_dn, match: dn, labels: {cn: agreement}}`,
+			joined: []*ldap.Entry{
+				ldap.NewEntry("cn=agmt1,cn=replica,cn=dc\\3Dexample\\2Cdc\\3Dcom,cn=mapping tree,cn=config", map[string][]string{"cn": {"agmt1"}}),
+			},
+			dn:       "cn=Replica,cn=dc\\=example\\,dc\\=com,cn=mapping tree,cn=config",
+			expected: map[string]string{"agreement": "agmt1"},
+		},
+		{
+			config:   `{search: cn=config, match: parent_dn, labels: {nsslapd-suffix: suffix}}`,
+			joined:   backends,
+			dn:       "cn=monitor,cn=other,cn=ldbm database,cn=plugins,cn=config",
+			expected: map[string]string{"suffix": ""},
+		},
+		{
+			config: `{search: cn=config, key: nsslapd-suffix, match: replicaroot, labels: {cn: backend}}`,
+			joined: []*ldap.Entry{
+				ldap.NewEntry("cn=userRoot,cn=ldbm database,cn=plugins,cn=config", map[string][]string{"cn": {"userRoot"}, "nsslapd-suffix": {"dc=example,dc=com"}}),
+			},
+			dn:       "cn=replica,cn=mapping tree,cn=config",
+			attrs:    map[string][]string{"replicaroot": {"DC=Example, DC=Com"}},
+			expected: map[string]string{"backend": "userRoot"},
+		},
+	} {
+		var config joinConfig
+		if err := yaml.Unmarshal([]byte(tc.config), &config); err != nil {
+			t.Errorf("join %s failed: %s", tc.config, err)
+			continue
+		}
+		index, err := config.join.index(tc.joined)
+		if err != nil {
+			t.Errorf("join %s index failed: %s", tc.config, err)
+			continue
+		}
+		labels, err := config.join.lookup(index, ldap.NewEntry(tc.dn, tc.attrs))
+		if err != nil {
+			t.Errorf("join %s lookup of %q failed: %s", tc.config, tc.dn, err)
+		} else if !reflect.DeepEqual(labels, tc.expected) {
+			t.Errorf("join %s lookup of %q = %v, expected %v", tc.config, tc.dn, labels, tc.expected)
+		}
+	}
+
+	var config joinConfig
+	if err := yaml.Unmarshal([]byte(`{search: cn=config, match: parent_dn, labels: {cn: name}}`), &config); err != nil {
+		t.Fatalf("join failed: %s", err)
+	}
+	if _, err := config.join.index([]*ldap.Entry{
+		ldap.NewEntry("cn=a,cn=config", map[string][]string{"cn": {"a"}}),
+		ldap.NewEntry("CN=A, cn=Config", map[string][]string{"cn": {"A"}}),
+	}); err == nil {
+		t.Errorf("joined entries with the same normalized key should fail")
+	}
+}
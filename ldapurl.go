@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gopkg.in/ldap.v2"
+)
+
+// ldapURL is a parsed RFC 4516 LDAP URL: scheme://host/dn?attributes?scope?filter?extensions
+type ldapURL struct {
+	Scheme     string
+	Host       string
+	DN         string
+	Attributes []string
+	// -1 if the URL didn't specify one; RFC 4516 defaults it to base.
+	Scope      int
+	Filter     string
+	Extensions []ldapURLExtension
+}
+
+type ldapURLExtension struct {
+	Critical bool
+	Type     string
+	Value    string
+}
+
+var ldapURLScopes = map[string]int{
+	"base": ldap.ScopeBaseObject,
+	"one":  ldap.ScopeSingleLevel,
+	"sub":  ldap.ScopeWholeSubtree,
+}
+
+func parseLDAPURL(raw string) (*ldapURL, error) {
+	u := &ldapURL{Scope: -1}
+	idx := strings.Index(raw, "://")
+	if idx < 0 {
+		return nil, fmt.Errorf("ldap url %q has no scheme", raw)
+	}
+	u.Scheme = strings.ToLower(raw[:idx])
+	switch u.Scheme {
+	case "ldap", "ldaps", "ldapi":
+	default:
+		return nil, fmt.Errorf("ldap url %q has unsupported scheme %s", raw, u.Scheme)
+	}
+	rest := raw[idx+3:]
+	if idx = strings.Index(rest, "/"); idx < 0 {
+		u.Host = rest
+		rest = ""
+	} else {
+		u.Host = rest[:idx]
+		rest = rest[idx+1:]
+	}
+	var err error
+	if u.Host, err = url.PathUnescape(u.Host); err != nil {
+		return nil, fmt.Errorf("ldap url %q has a malformed host: %s", raw, err)
+	}
+
+	// the components can't contain a literal ?, so splitting before unescaping is safe.
+	parts := strings.Split(rest, "?")
+	if len(parts) > 5 {
+		return nil, fmt.Errorf("ldap url %q has too many components", raw)
+	}
+	for len(parts) < 5 {
+		parts = append(parts, "")
+	}
+	if u.DN, err = url.PathUnescape(parts[0]); err != nil {
+		return nil, fmt.Errorf("ldap url %q has a malformed dn: %s", raw, err)
+	}
+	if parts[1] != "" {
+		for _, attr := range strings.Split(parts[1], ",") {
+			if attr, err = url.PathUnescape(attr); err != nil || attr == "" {
+				return nil, fmt.Errorf("ldap url %q has a malformed attribute list", raw)
+			}
+			u.Attributes = append(u.Attributes, attr)
+		}
+	}
+	if parts[2] != "" {
+		scope, ok := ldapURLScopes[strings.ToLower(parts[2])]
+		if !ok {
+			return nil, fmt.Errorf("ldap url %q has unknown scope %s; supported options are base, one, and sub", raw, parts[2])
+		}
+		u.Scope = scope
+	}
+	if u.Filter, err = url.PathUnescape(parts[3]); err != nil {
+		return nil, fmt.Errorf("ldap url %q has a malformed filter: %s", raw, err)
+	}
+	if parts[4] != "" {
+		for _, ext := range strings.Split(parts[4], ",") {
+			var e ldapURLExtension
+			if strings.HasPrefix(ext, "!") {
+				e.Critical = true
+				ext = ext[1:]
+			}
+			if idx := strings.Index(ext, "="); idx >= 0 {
+				if e.Value, err = url.PathUnescape(ext[idx+1:]); err != nil {
+					return nil, fmt.Errorf("ldap url %q has a malformed extension: %s", raw, err)
+				}
+				ext = ext[:idx]
+			}
+			if ext == "" {
+				return nil, fmt.Errorf("ldap url %q has an extension lacking a type", raw)
+			}
+			e.Type = ext
+			u.Extensions = append(u.Extensions, e)
+		}
+	}
+	return u, nil
+}
+
+// escapeLDAPURLComponent percent encodes what can't appear literally in a URL component;
+// commas are only escaped within lists.
+func escapeLDAPURLComponent(s string, in_list bool) string {
+	var b strings.Builder
+	for idx := 0; idx < len(s); idx++ {
+		c := s[idx]
+		switch {
+		case c == '%' || c == '?' || c == '#' || c == ' ' || c < 0x21 || c > 0x7e:
+			fmt.Fprintf(&b, "%%%02X", c)
+		case c == ',' && in_list:
+			b.WriteString("%2C")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (u *ldapURL) String() string {
+	scheme := u.Scheme
+	if scheme == "" {
+		scheme = "ldap"
+	}
+	var attrs []string
+	for _, attr := range u.Attributes {
+		attrs = append(attrs, escapeLDAPURLComponent(attr, true))
+	}
+	scope := ""
+	for name, value := range ldapURLScopes {
+		if value == u.Scope {
+			scope = name
+		}
+	}
+	var exts []string
+	for _, e := range u.Extensions {
+		ext := escapeLDAPURLComponent(e.Type, true)
+		if e.Critical {
+			ext = "!" + ext
+		}
+		if e.Value != "" {
+			ext += "=" + escapeLDAPURLComponent(e.Value, true)
+		}
+		exts = append(exts, ext)
+	}
+	s := fmt.Sprintf("%s://%s/%s?%s?%s?%s", scheme, u.Host, escapeLDAPURLComponent(u.DN, false), strings.Join(attrs, ","), scope, escapeLDAPURLComponent(u.Filter, false))
+	if len(exts) != 0 {
+		s += "?" + strings.Join(exts, ",")
+	}
+	return s
+}
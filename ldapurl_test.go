@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/ldap.v2"
+)
+
+func TestParseLDAPURL(t *testing.T) {
+	for raw, expected := range map[string]ldapURL{
+		"ldap://":                      {Scheme: "ldap", Scope: -1},
+		"ldap:///":                     {Scheme: "ldap", Scope: -1},
+		"LDAPS://ldap.example.com:636": {Scheme: "ldaps", Host: "ldap.example.com:636", Scope: -1},
+		"ldap://ldap.example.com/dc=example,dc=com?cn,mail?sub?(uid=a)": {
+			Scheme:     "ldap",
+			Host:       "ldap.example.com",
+			DN:         "dc=example,dc=com",
+			Attributes: []string{"cn", "mail"},
+			Scope:      ldap.ScopeWholeSubtree,
+			Filter:     "(uid=a)",
+		},
+		"ldapi://%2Fvar%2Frun%2Fslapd%2Fldapi/cn=monitor??base": {
+			Scheme: "ldapi",
+			Host:   "/var/run/slapd/ldapi",
+			DN:     "cn=monitor",
+			Scope:  ldap.ScopeBaseObject,
+		},
+		"ldap:///cn=ldbm%20database,cn=plugins,cn=config??ONE?(cn=%3F%25)": {
+			Scheme: "ldap",
+			DN:     "cn=ldbm database,cn=plugins,cn=config",
+			Scope:  ldap.ScopeSingleLevel,
+			Filter: "(cn=?%)",
+		},
+		"ldap:///o=a%2Cb?x%2Cy": {
+			Scheme:     "ldap",
+			DN:         "o=a,b",
+			Attributes: []string{"x,y"},
+			Scope:      -1,
+		},
+		"ldap:///????!1.2.3=a%2Cb,e-bindname": {
+			Scheme: "ldap",
+			Scope:  -1,
+			Extensions: []ldapURLExtension{
+				{Critical: true, Type: "1.2.3", Value: "a,b"},
+				{Type: "e-bindname"},
+			},
+		},
+	} {
+		u, err := parseLDAPURL(raw)
+		if err != nil {
+			t.Errorf("parseLDAPURL(%q) failed: %s", raw, err)
+		} else if !reflect.DeepEqual(*u, expected) {
+			t.Errorf("parseLDAPURL(%q) = %+v, expected %+v", raw, *u, expected)
+		}
+	}
+	for _, raw := range []string{
+		"ldap.example.com",
+		"http://ldap.example.com/",
+		"ldap:///?a,,b",
+		"ldap:///??subtree",
+		"ldap:///????=value",
+		"ldap:///?????",
+		"ldap:///cn=%zz",
+	} {
+		if _, err := parseLDAPURL(raw); err == nil {
+			t.Errorf("parseLDAPURL(%q) should fail", raw)
+		}
+	}
+}
+
+func TestLDAPURLString(t *testing.T) {
+	for expected, u := range map[string]ldapURL{
+		"ldap:///???": {Scope: -1},
+		"ldaps://ldap.example.com/dc=example,dc=com?cn,mail?sub?(uid=a)": {
+			Scheme:     "ldaps",
+			Host:       "ldap.example.com",
+			DN:         "dc=example,dc=com",
+			Attributes: []string{"cn", "mail"},
+			Scope:      ldap.ScopeWholeSubtree,
+			Filter:     "(uid=a)",
+		},
+		"ldap:///cn=ldbm%20database,cn=config?a%2Cb?one?(cn=%3F%25%23)": {
+			DN:         "cn=ldbm database,cn=config",
+			Attributes: []string{"a,b"},
+			Scope:      ldap.ScopeSingleLevel,
+			Filter:     "(cn=?%#)",
+		},
+		"ldap:///???(cn=caf%C3%A9)?!1.2.3=a%2Cb,e-bindname": {
+			Scope:  -1,
+			Filter: "(cn=café)",
+			Extensions: []ldapURLExtension{
+				{Critical: true, Type: "1.2.3", Value: "a,b"},
+				{Type: "e-bindname"},
+			},
+		},
+	} {
+		if value := u.String(); value != expected {
+			t.Errorf("String() of %+v = %q, expected %q", u, value, expected)
+		}
+	}
+}
+
+func TestLDAPURLRoundTrip(t *testing.T) {
+	for _, raw := range []string{
+		"ldap:///???",
+		"ldaps://ldap.example.com:636/dc=example,dc=com?cn,mail?sub?(uid=a)",
+		"ldap:///cn=monitor?monitorCounter?base?(objectClass=*)",
+		"ldap:///cn=a%20b,c?x%2Cy?one?(cn=%3F%25)?!1.2.3=a%2Cb,e-bindname",
+	} {
+		u, err := parseLDAPURL(raw)
+		if err != nil {
+			t.Errorf("parseLDAPURL(%q) failed: %s", raw, err)
+			continue
+		}
+		if value := u.String(); value != raw {
+			t.Errorf("parseLDAPURL(%q).String() = %q", raw, value)
+		}
+		again, err := parseLDAPURL(u.String())
+		if err != nil {
+			t.Errorf("reparsing %q failed: %s", u.String(), err)
+		} else if !reflect.DeepEqual(again, u) {
+			t.Errorf("reparsing %q = %+v, expected %+v", u.String(), *again, *u)
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
@@ -25,12 +27,33 @@ var (
 	ldap_tls_cert       = flag.String("ldap.tls.cert-file", "", "If the server requires a client cert, the path to that TLS cert.  If this is passed, -ldap.tls.key-file must also be passed")
 	ldap_tls_key        = flag.String("ldap.tls.key-file", "", "If the server requires a client key, the path to that TLS key.  If this is passed, -ldap.tls.cert-file must also be passed")
 	ldap_tls_serverName = flag.String("ldap.tls.server-name", "", "If specified, expect this name for TLS handshakes rather than using the hostname parsed from -ldap.uri")
+	ldap_startTLS       = flag.Bool("ldap.start-tls", false, "If given, upgrade ldap:// connections to TLS via StartTLS.  The -ldap.tls options apply as they do for ldaps://")
 	ldap_tls_skipVerify = flag.Bool("ldap.tls.skip-verify", false, "If given, do not do any verification of the server's cert.  Insecure and allows for MITM")
+	ldap_tls_systemCA   = flag.Bool("ldap.tls.system-ca", false, "If given with -ldap.tls.ca-file, trust the system's CAs as well rather than only that file's")
+	ldap_tls_minVersion = flag.String("ldap.tls.min-version", "", "The minimum TLS version to negotiate; one of 1.0, 1.1, 1.2, or 1.3")
+	ldap_tls_maxVersion = flag.String("ldap.tls.max-version", "", "The maximum TLS version to negotiate; one of 1.0, 1.1, 1.2, or 1.3")
+	ldap_tls_ciphers    = flag.String("ldap.tls.cipher-suites", "", "Comma separated cipher suites to allow, such as TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384.  TLS 1.3 suites aren't configurable")
+	ldap_tls_pinSHA256  = flag.String("ldap.tls.pin-sha256", "", "Comma separated base64 SHA-256 hashes of public keys (SPKI) to pin.  The server is accepted if its leaf certificate's key matches, instead of verifying it against CAs; useful for self signed certs")
 	ldap_bind           = flag.String("ldap.bind", "", "Ldap DN to bind to")
 	ldap_password       = flag.String("ldap.password", os.Getenv("LDAP_PASSWORD"), "LDAP bind DN password.  Can be configured via the environment variable LDAP_PASSWORD")
 
+	ldap_tls_checkInterval = flag.Duration("ldap.tls.check-interval", time.Minute, "How often to handshake with -ldap.uri afresh so the TLS metrics notice a renewed certificate; 0 handshakes at every scrape")
+
 	disableVendorMetrics = flag.Bool("metrics.disable-vendor-metrics", false, "By default, try to identify the LDAP vendor and load metrics for thhat vendor.  If the vendor cannot be identified or if this is enabled,, -metrics.config must be set.")
 	queryFile            = flag.String("metrics.config", "", "YAML file holding ldap -> metrics queries.  Note if the LDAP vendor cannot be identified, this must be set")
+	printURLs            = flag.Bool("metrics.print-urls", false, "Print the search of every configured source as an LDAP URL, then exit.  Useful for reproducing a source's search via ldapsearch -H")
+
+	probeBindDN           = flag.String("probe.bind.dn", "", "If given, bind as this DN over a fresh connection at every scrape and report whether it succeeded.  Requires -probe.bind.password-file")
+	probeBindPasswordFile = flag.String("probe.bind.password-file", "", "File holding the password of -probe.bind.dn; reread at every scrape")
+	probeWriteDN          = flag.String("probe.write.dn", "", "If given, write to this canary entry at every scrape and report whether it succeeded.  The -ldap.bind DN must be allowed to write it")
+	probeWriteMode        = flag.String("probe.write.mode", "modify", "How -probe.write.dn is written; modify replaces -probe.write.attribute of an existing entry, add adds then deletes the entry")
+	probeWriteAttribute   = flag.String("probe.write.attribute", "description", "The attribute of -probe.write.dn the current time is written to")
+	probeWriteInterval    = flag.Duration("probe.write.interval", 0, "If nonzero, write at most this often; scrapes in between report the last write")
+
+	probeReplicationDN        = flag.String("probe.replication.dn", "", "If given, write the time to this canary entry via -ldap.uri at every scrape and measure how long it takes to reach each of -probe.replication.consumers")
+	probeReplicationConsumers = flag.String("probe.replication.consumers", "", "Comma separated URIs of the replicas -probe.replication.dn is polled on; they're bound to with -ldap.bind")
+	probeReplicationAttribute = flag.String("probe.replication.attribute", "description", "The attribute of -probe.replication.dn the time is written to")
+	probeReplicationTimeout   = flag.Duration("probe.replication.timeout", 10*time.Second, "How long to wait for a consumer to converge before reporting it as timed out; keep it below the scrape timeout")
 )
 
 func createTLSConfigFromFlags() (*tls.Config, error) {
@@ -42,10 +65,18 @@ func createTLSConfigFromFlags() (*tls.Config, error) {
 		if err != nil {
 			return nil, err
 		}
-		ca_pool = x509.NewCertPool()
+		if *ldap_tls_systemCA {
+			if ca_pool, err = x509.SystemCertPool(); err != nil {
+				return nil, fmt.Errorf("failed loading the system CAs: %s", err)
+			}
+		} else {
+			ca_pool = x509.NewCertPool()
+		}
 		if !ca_pool.AppendCertsFromPEM(ca_content) {
 			return nil, fmt.Errorf("failed to read ca_file %v in PEM format", *ldap_tls_ca)
 		}
+	} else if *ldap_tls_systemCA {
+		return nil, fmt.Errorf("passed -ldap.tls.system-ca but required -ldap.tls.ca-file wasn't passed")
 	}
 
 	if *ldap_tls_cert != "" {
@@ -65,40 +96,86 @@ func createTLSConfigFromFlags() (*tls.Config, error) {
 		RootCAs:            ca_pool,
 		Certificates:       certs,
 	}
+	var err error
+	if *ldap_tls_minVersion != "" {
+		if config.MinVersion, err = parseTLSVersion(*ldap_tls_minVersion); err != nil {
+			return nil, err
+		}
+	}
+	if *ldap_tls_maxVersion != "" {
+		if config.MaxVersion, err = parseTLSVersion(*ldap_tls_maxVersion); err != nil {
+			return nil, err
+		}
+	}
+	if config.MinVersion != 0 && config.MaxVersion != 0 && config.MinVersion > config.MaxVersion {
+		return nil, fmt.Errorf("-ldap.tls.min-version %s is above -ldap.tls.max-version %s", *ldap_tls_minVersion, *ldap_tls_maxVersion)
+	}
+	if config.CipherSuites, err = parseCipherSuites(*ldap_tls_ciphers); err != nil {
+		return nil, err
+	}
+	pins, err := parseSPKIPins(*ldap_tls_pinSHA256)
+	if err != nil {
+		return nil, err
+	}
+	if len(pins) != 0 {
+		if *ldap_tls_skipVerify {
+			return nil, fmt.Errorf("-ldap.tls.pin-sha256 and -ldap.tls.skip-verify are mutually exclusive")
+		}
+		// the pins replace verification against CAs, which tls only allows skipping entirely.
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = verifySPKIPins(pins)
+	}
 	return config, nil
 }
 
-func createLdapClientFromFlags(ldap_uri string, serverName string, tls_config *tls.Config) (*ldap.Conn, error) {
+// tlsServerName is the name the server's certificate is expected to have.
+func tlsServerName(u *url.URL, serverName string) string {
+	if serverName != "" {
+		return serverName
+	}
+	return u.Hostname()
+}
+
+// createLdapClientFromFlags connects to the server; the TLS state is nil unless TLS is in use.
+func createLdapClientFromFlags(ldap_uri string, serverName string, tls_config *tls.Config, start_tls bool) (*ldap.Conn, *tls.ConnectionState, error) {
 	if ldap_uri == "" {
-		return nil, fmt.Errorf("-ldap.uri is a required argument")
+		return nil, nil, fmt.Errorf("-ldap.uri is a required argument")
 	}
 	u, err := url.Parse(ldap_uri)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if u.Scheme == "ldapi" {
-		return ldap.Dial("unix", u.Path)
-	} else if u.Scheme == "ldap" {
-		port := u.Port()
-		if port == "" {
-			port = "389"
-		}
-		return ldap.Dial("tcp", net.JoinHostPort(u.Hostname(), port))
-	} else if u.Scheme == "ldaps" {
-		// build our tls configuration.
-		port := u.Port()
-		if port == "" {
-			port = "636"
+		if start_tls {
+			return nil, nil, fmt.Errorf("StartTLS isn't supported for ldapi://")
 		}
-		// This should be handled by createTLSConfigFromFlags...
-		if serverName != "" {
-			tls_config.ServerName = serverName
-		} else {
-			tls_config.ServerName = u.Hostname()
+		conn, err := ldap.Dial("unix", u.Path)
+		return conn, nil, err
+	} else if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, nil, fmt.Errorf("unsupported ldap scheme %v", u.Scheme)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "389"
+		if u.Scheme == "ldaps" {
+			port = "636"
 		}
-		return ldap.DialTLS("tcp", net.JoinHostPort(u.Hostname(), port), tls_config)
 	}
-	return nil, fmt.Errorf("unsupported ldap scheme %v", u.Scheme)
+	if u.Scheme == "ldaps" && start_tls {
+		return nil, nil, fmt.Errorf("StartTLS isn't supported for ldaps://, which is already TLS")
+	}
+	if u.Scheme == "ldap" && !start_tls {
+		conn, err := ldap.Dial("tcp", net.JoinHostPort(u.Hostname(), port))
+		return conn, nil, err
+	}
+	// cloned since the config is shared by dials that run concurrently during scrapes.
+	tls_config = tls_config.Clone()
+	tls_config.ServerName = tlsServerName(u, serverName)
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(u.Hostname(), port), ldap.DefaultTimeout)
+	if err != nil {
+		return nil, nil, ldap.NewError(ldap.ErrorNetwork, err)
+	}
+	return dialTLS(conn, tls_config, start_tls)
 }
 
 func main() {
@@ -108,10 +185,24 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	client, err := createLdapClientFromFlags(*ldap_uri, *ldap_tls_serverName, tls_config)
+	client, tls_state, err := createLdapClientFromFlags(*ldap_uri, *ldap_tls_serverName, tls_config, *ldap_startTLS)
 	if err != nil {
 		log.Fatal(err)
 	}
+	// parsing can't fail since the client was created from it.
+	parsed_uri, _ := url.Parse(*ldap_uri)
+	// pinning also sets InsecureSkipVerify, but the pins are enforced instead.
+	check := func() (*tls.ConnectionState, error) {
+		// only the handshake matters, so the connection is closed without binding.
+		conn, tls_state, err := createLdapClientFromFlags(*ldap_uri, *ldap_tls_serverName, tls_config, *ldap_startTLS)
+		if err != nil {
+			return nil, err
+		}
+		conn.Close()
+		return tls_state, nil
+	}
+	tls_collector := newTLSCollector(tls_config, tlsServerName(parsed_uri, *ldap_tls_serverName), !*ldap_tls_skipVerify, check, *ldap_tls_checkInterval)
+	tls_collector.record(tls_state)
 
 	if *ldap_bind != "" {
 		if *ldap_password == "" {
@@ -155,8 +246,78 @@ func main() {
 	if len(sources) == 0 {
 		log.Fatal("no metrics were configured; nothing to export")
 	}
-	e := NewExporter(client, sources)
+	if *printURLs {
+		for _, source := range sources {
+			if source.NamingContexts {
+				root_dse := &ldapURL{Attributes: []string{"namingContexts"}, Scope: ldap.ScopeBaseObject, Filter: "(objectClass=*)"}
+				fmt.Printf("%s\t# searched under each of the rootDSE's namingContexts, listed by %s\n", source.Name, root_dse)
+			}
+			for _, u := range source.URLs() {
+				fmt.Printf("%s\t%s\n", source.Name, u)
+			}
+		}
+		return
+	}
+	dial := func() (*ldap.Conn, error) {
+		conn, _, err := createLdapClientFromFlags(*ldap_uri, *ldap_tls_serverName, tls_config, *ldap_startTLS)
+		return conn, err
+	}
+	e := NewExporter(client, dial, sources)
 	prometheus.MustRegister(e)
+	if tls_state != nil {
+		prometheus.MustRegister(tls_collector)
+	}
+
+	if *probeBindDN != "" {
+		if *probeBindPasswordFile == "" {
+			log.Fatal("-probe.bind.dn given, but -probe.bind.password-file wasn't")
+		}
+		identity := bindIdentity{dn: *probeBindDN, passwordFile: *probeBindPasswordFile}
+		if _, err := identity.password(); err != nil {
+			log.Fatal(err)
+		}
+		prometheus.MustRegister(newBindProbe(dial, identity))
+	} else if *probeBindPasswordFile != "" {
+		log.Fatal("-probe.bind.password-file given, but -probe.bind.dn wasn't")
+	}
+
+	if *probeWriteDN != "" {
+		probe, err := newWriteProbe(client, *probeWriteDN, *probeWriteMode, *probeWriteAttribute, *probeWriteInterval)
+		if err != nil {
+			log.Fatal(err)
+		}
+		prometheus.MustRegister(probe)
+	}
+
+	if *probeReplicationDN != "" {
+		var consumers []string
+		for _, consumer := range strings.Split(*probeReplicationConsumers, ",") {
+			if consumer = strings.TrimSpace(consumer); consumer != "" {
+				consumers = append(consumers, consumer)
+			}
+		}
+		dialConsumer := func(uri string) (*ldap.Conn, error) {
+			// -ldap.tls.server-name is for -ldap.uri; consumers are verified against their own hostname.
+			conn, _, err := createLdapClientFromFlags(uri, "", tls_config, *ldap_startTLS)
+			if err != nil {
+				return nil, err
+			}
+			if *ldap_bind != "" {
+				if err := conn.Bind(*ldap_bind, *ldap_password); err != nil {
+					conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		}
+		probe, err := newReplicationProbe(client, *ldap_uri, consumers, dialConsumer, *probeReplicationDN, *probeReplicationAttribute, *probeReplicationTimeout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		prometheus.MustRegister(probe)
+	} else if *probeReplicationConsumers != "" {
+		log.Fatal("-probe.replication.consumers given, but -probe.replication.dn wasn't")
+	}
 
 	log.Infof("starting server; telemetry accessible at %s%s", *listen, *metricsPath)
 	http.Handle(*metricsPath, promhttp.Handler())
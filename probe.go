@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/ldap.v2"
+)
+
+// resultCode is the LDAP result code of an operation's error; errors that aren't from the
+// server, such as connection failures, are reported as the client's ErrorNetwork.
+func resultCode(err error) int {
+	if err == nil {
+		return ldap.LDAPResultSuccess
+	}
+	if ldapErr, ok := err.(*ldap.Error); ok {
+		return int(ldapErr.ResultCode)
+	}
+	return ldap.ErrorNetwork
+}
+
+// bindProbe binds with test credentials over a fresh connection at every scrape, catching
+// authentication breaking after the exporter's own startup bind.  Only simple binds are
+// supported since the ldap client lacks SASL.
+type bindProbe struct {
+	dial     func() (*ldap.Conn, error)
+	identity bindIdentity
+
+	success    *prometheus.Desc
+	duration   *prometheus.Desc
+	resultCode *prometheus.Desc
+}
+
+func newBindProbe(dial func() (*ldap.Conn, error), identity bindIdentity) *bindProbe {
+	return &bindProbe{
+		dial:     dial,
+		identity: identity,
+		success: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "bind_success"),
+			"Whether the probe's bind succeeded.",
+			nil, nil,
+		),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "bind_duration_seconds"),
+			"How long the probe's bind took, excluding connecting.",
+			nil, nil,
+		),
+		resultCode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "bind_result_code"),
+			"The LDAP result code of the probe's bind; codes 200 and above are client side failures such as the connection failing.",
+			nil, nil,
+		),
+	}
+}
+
+func (p *bindProbe) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.success
+	ch <- p.duration
+	ch <- p.resultCode
+}
+
+func (p *bindProbe) Collect(ch chan<- prometheus.Metric) {
+	success := float64(0)
+	defer func() {
+		ch <- prometheus.MustNewConstMetric(p.success, prometheus.GaugeValue, success)
+	}()
+	// a missing password is a configuration problem rather than a result of the bind.
+	password, err := p.identity.password()
+	if err != nil {
+		log.Errorf("bind probe failed: %s", err)
+		return
+	}
+	err = p.bind(ch, password)
+	if err != nil {
+		log.Errorf("bind probe as %s failed: %s", p.identity, err)
+	} else {
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(p.resultCode, prometheus.GaugeValue, float64(resultCode(err)))
+}
+
+// bind connects and binds once; the duration is only emitted if the connection succeeded.
+func (p *bindProbe) bind(ch chan<- prometheus.Metric, password string) error {
+	conn, err := p.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	begin := time.Now()
+	err = conn.Bind(p.identity.dn, password)
+	ch <- prometheus.MustNewConstMetric(p.duration, prometheus.GaugeValue, time.Since(begin).Seconds())
+	return err
+}
+
+const (
+	writeProbeModify = "modify"
+	writeProbeAdd    = "add"
+)
+
+// writeProbe exercises the write path, which searches of cn=monitor happily succeed without;
+// a read-only database or a wedged changelog only shows up once something is written.  In
+// modify mode the attribute of an existing entry is replaced with the current time; in add
+// mode the entry is added with the time in the attribute, then deleted.  Writes use the
+// exporter's connection, so -ldap.bind must be allowed to write the entry.
+type writeProbe struct {
+	conn      *ldap.Conn
+	dn        string
+	mode      string
+	attribute string
+	// the value of the dn's cn rdn, which add mode must also give as an attribute.
+	cn string
+	// if nonzero, scrapes within interval of the last write report its results.
+	interval time.Duration
+
+	lock    sync.Mutex
+	last    time.Time
+	results []writeProbeResult
+
+	success    *prometheus.Desc
+	duration   *prometheus.Desc
+	resultCode *prometheus.Desc
+}
+
+type writeProbeResult struct {
+	operation string
+	duration  time.Duration
+	err       error
+}
+
+func newWriteProbe(conn *ldap.Conn, dn string, mode string, attribute string, interval time.Duration) (*writeProbe, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return nil, fmt.Errorf("write probe dn %q is malformed: %s", dn, err)
+	}
+	cn := ""
+	switch mode {
+	case writeProbeModify:
+	case writeProbeAdd:
+		// the entry is added as an applicationProcess, which every server's core schema has.
+		if len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) != 1 || !strings.EqualFold(parsed.RDNs[0].Attributes[0].Type, "cn") {
+			return nil, fmt.Errorf("write probe dn %q must have a cn rdn in add mode", dn)
+		}
+		cn = parsed.RDNs[0].Attributes[0].Value
+	default:
+		return nil, fmt.Errorf("write probe mode %s is unknown; supported options are %s and %s", mode, writeProbeModify, writeProbeAdd)
+	}
+	if attribute == "" {
+		return nil, fmt.Errorf("write probe attribute cannot be empty")
+	}
+	if mode == writeProbeAdd && strings.EqualFold(attribute, "cn") {
+		return nil, fmt.Errorf("write probe attribute cannot be cn in add mode, since it holds the rdn")
+	}
+	labels := []string{"operation"}
+	return &writeProbe{
+		conn:      conn,
+		dn:        dn,
+		mode:      mode,
+		attribute: attribute,
+		cn:        cn,
+		interval:  interval,
+		success: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "write_success"),
+			"Whether the write probe's operation succeeded.",
+			labels, nil,
+		),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "write_duration_seconds"),
+			"How long the write probe's operation took.",
+			labels, nil,
+		),
+		resultCode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "write_result_code"),
+			"The LDAP result code of the write probe's operation; codes 200 and above are client side failures such as the connection failing.",
+			labels, nil,
+		),
+	}, nil
+}
+
+func (p *writeProbe) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.success
+	ch <- p.duration
+	ch <- p.resultCode
+}
+
+func (p *writeProbe) Collect(ch chan<- prometheus.Metric) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.results == nil || time.Since(p.last) >= p.interval {
+		p.last = time.Now()
+		p.results = p.write(p.last)
+	}
+	for _, result := range p.results {
+		success := float64(1)
+		if result.err != nil {
+			success = 0
+		}
+		ch <- prometheus.MustNewConstMetric(p.success, prometheus.GaugeValue, success, result.operation)
+		ch <- prometheus.MustNewConstMetric(p.duration, prometheus.GaugeValue, result.duration.Seconds(), result.operation)
+		ch <- prometheus.MustNewConstMetric(p.resultCode, prometheus.GaugeValue, float64(resultCode(result.err)), result.operation)
+	}
+}
+
+// write runs the probe's operations, stopping at the first failure.
+func (p *writeProbe) write(now time.Time) []writeProbeResult {
+	value := now.UTC().Format("20060102150405Z")
+	if p.mode == writeProbeModify {
+		modify := ldap.NewModifyRequest(p.dn)
+		modify.Replace(p.attribute, []string{value})
+		return []writeProbeResult{p.run("modify", func() error { return p.conn.Modify(modify) })}
+	}
+
+	add := ldap.NewAddRequest(p.dn)
+	add.Attribute("objectClass", []string{"top", "applicationProcess"})
+	// servers don't derive the naming attribute from the rdn; without it the add is a namingViolation.
+	add.Attribute("cn", []string{p.cn})
+	add.Attribute(p.attribute, []string{value})
+	result := p.run("add", func() error { return p.conn.Add(add) })
+	if ldap.IsErrorWithCode(result.err, ldap.LDAPResultEntryAlreadyExists) {
+		// left behind by an earlier delete failing; clear it so the probe can recover.
+		log.Warnf("write probe entry %s already exists; deleting it", p.dn)
+		if err := p.conn.Del(ldap.NewDelRequest(p.dn, nil)); err != nil {
+			log.Errorf("write probe failed deleting the stale %s: %s", p.dn, err)
+		} else {
+			result = p.run("add", func() error { return p.conn.Add(add) })
+		}
+	}
+	if result.err != nil {
+		return []writeProbeResult{result}
+	}
+	return []writeProbeResult{result, p.run("delete", func() error { return p.conn.Del(ldap.NewDelRequest(p.dn, nil)) })}
+}
+
+func (p *writeProbe) run(operation string, f func() error) writeProbeResult {
+	begin := time.Now()
+	err := f()
+	if err != nil {
+		log.Errorf("write probe %s of %s failed: %s", operation, p.dn, err)
+	}
+	return writeProbeResult{operation: operation, duration: time.Since(begin), err: err}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/ldap.v2"
+)
+
+// how often consumers are searched for the canary's value while waiting on replication.
+const replicationPollInterval = 100 * time.Millisecond
+
+// replicationProbe measures how long a change takes to reach each replica, which agreement
+// status can't tell; it only says the last update succeeded.  At every scrape the current
+// time is written to a canary entry on the supplier, then each consumer is polled until its
+// copy of the entry has the new value or the timeout passes.
+type replicationProbe struct {
+	supplier    *ldap.Conn
+	source      string
+	consumers   []string
+	dial        func(uri string) (*ldap.Conn, error)
+	dn          string
+	attribute   string
+	timeout     time.Duration
+	propagation *prometheus.Desc
+	timedOut    *prometheus.Desc
+	success     *prometheus.Desc
+}
+
+func newReplicationProbe(supplier *ldap.Conn, source string, consumers []string, dial func(uri string) (*ldap.Conn, error), dn string, attribute string, timeout time.Duration) (*replicationProbe, error) {
+	if _, err := ldap.ParseDN(dn); err != nil {
+		return nil, fmt.Errorf("replication probe dn %q is malformed: %s", dn, err)
+	}
+	if len(consumers) == 0 {
+		return nil, fmt.Errorf("replication probe requires at least one consumer")
+	}
+	if attribute == "" {
+		return nil, fmt.Errorf("replication probe attribute cannot be empty")
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("replication probe timeout must be positive")
+	}
+	labels := []string{"source", "target"}
+	return &replicationProbe{
+		supplier:  supplier,
+		source:    source,
+		consumers: consumers,
+		dial:      dial,
+		dn:        dn,
+		attribute: attribute,
+		timeout:   timeout,
+		propagation: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "replication", "propagation_seconds"),
+			"How long the canary's change took to reach the target; absent if it didn't within the timeout.",
+			labels, nil,
+		),
+		timedOut: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "replication", "propagation_timeout"),
+			"Whether the canary's change failed to reach the target within the timeout, including the target being unreachable.",
+			labels, nil,
+		),
+		success: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "replication", "canary_write_success"),
+			"Whether the canary was written to the source; if not, nothing is measured.",
+			[]string{"source"}, nil,
+		),
+	}, nil
+}
+
+func (p *replicationProbe) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.propagation
+	ch <- p.timedOut
+	ch <- p.success
+}
+
+func (p *replicationProbe) Collect(ch chan<- prometheus.Metric) {
+	// consumers are connected before the write so setting up the connections isn't counted
+	// as propagation time.
+	conns := make([]*ldap.Conn, len(p.consumers))
+	errs := make([]error, len(p.consumers))
+	var wg sync.WaitGroup
+	for idx, consumer := range p.consumers {
+		wg.Add(1)
+		go func(idx int, consumer string) {
+			defer wg.Done()
+			conns[idx], errs[idx] = p.dial(consumer)
+		}(idx, consumer)
+	}
+	wg.Wait()
+	defer func() {
+		for _, conn := range conns {
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}()
+
+	// nanoseconds so each scrape's value is distinct.
+	value := time.Now().UTC().Format(time.RFC3339Nano)
+	modify := ldap.NewModifyRequest(p.dn)
+	modify.Replace(p.attribute, []string{value})
+	if err := p.supplier.Modify(modify); err != nil {
+		log.Errorf("replication probe failed writing %s: %s", p.dn, err)
+		ch <- prometheus.MustNewConstMetric(p.success, prometheus.GaugeValue, 0, p.source)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(p.success, prometheus.GaugeValue, 1, p.source)
+	written := time.Now()
+
+	for idx, consumer := range p.consumers {
+		wg.Add(1)
+		go func(idx int, consumer string) {
+			defer wg.Done()
+			err := errs[idx]
+			var elapsed time.Duration
+			if err == nil {
+				elapsed, err = p.await(conns[idx], value, written)
+			}
+			timed_out := float64(0)
+			if err != nil {
+				log.Errorf("replication probe of %s: %s", consumer, err)
+				timed_out = 1
+			} else {
+				ch <- prometheus.MustNewConstMetric(p.propagation, prometheus.GaugeValue, elapsed.Seconds(), p.source, consumer)
+			}
+			ch <- prometheus.MustNewConstMetric(p.timedOut, prometheus.GaugeValue, timed_out, p.source, consumer)
+		}(idx, consumer)
+	}
+	wg.Wait()
+}
+
+// await polls the consumer until the canary has value, returning how long after written it did.
+func (p *replicationProbe) await(conn *ldap.Conn, value string, written time.Time) (time.Duration, error) {
+	request := ldap.NewSearchRequest(
+		p.dn,
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)",
+		[]string{p.attribute},
+		nil,
+	)
+	deadline := written.Add(p.timeout)
+	for {
+		result, err := conn.Search(request)
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			// the canary entry itself hasn't reached the consumer yet, so keep waiting.
+			result = &ldap.SearchResult{}
+		} else if err != nil {
+			return 0, err
+		}
+		if len(result.Entries) == 1 && result.Entries[0].GetAttributeValue(p.attribute) == value {
+			return time.Since(written), nil
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("%s didn't converge within %s", p.dn, p.timeout)
+		}
+		time.Sleep(replicationPollInterval)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/ldap.v2"
+)
+
+// searchTime is the scrape's reference time as given to search templates; it renders
+// as GeneralizedTime, so (pwdChangedTime<={{ .now.Add "-90d" }}) works as expected.
+type searchTime struct {
+	time time.Time
+}
+
+func (s searchTime) String() string {
+	return s.time.UTC().Format("20060102150405Z")
+}
+
+// Add offsets the time by a duration; see parseDuration for the accepted forms.
+func (s searchTime) Add(duration string) (searchTime, error) {
+	d, err := parseDuration(duration)
+	if err != nil {
+		return s, err
+	}
+	return searchTime{time: s.time.Add(d)}, nil
+}
+
+func (s searchTime) Unix() int64 {
+	return s.time.Unix()
+}
+
+// isSearchTemplate reports whether a search base or filter must be rendered at each scrape.
+func isSearchTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// searchTemplate is a search base or filter rendered at each scrape.  Templates are given
+// .now, the scrape's reference time, and .env, the exporter's environment variables.
+type searchTemplate struct {
+	template *template.Template
+	what     string
+	validate func(string) error
+}
+
+// newSearchTemplate returns nil if s isn't a template.  The template is rendered once to
+// catch errors at load rather than at the first scrape.
+func newSearchTemplate(what string, s string, validate func(string) error) (*searchTemplate, error) {
+	if !isSearchTemplate(s) {
+		return nil, nil
+	}
+	t, err := template.New(what).Funcs(templateFuncs()).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s template parse failure; error was %s, template was:\n%s", what, err, s)
+	}
+	st := &searchTemplate{template: t, what: what, validate: validate}
+	if _, err := st.render(time.Now()); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (t *searchTemplate) render(now time.Time) (string, error) {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if idx := strings.Index(kv, "="); idx > 0 {
+			env[kv[:idx]] = kv[idx+1:]
+		}
+	}
+	var buffer bytes.Buffer
+	if err := t.template.Execute(&buffer, map[string]interface{}{"now": searchTime{time: now}, "env": env}); err != nil {
+		return "", fmt.Errorf("failed rendering %s: %s", t.what, err)
+	}
+	rendered := buffer.String()
+	if err := t.validate(rendered); err != nil {
+		return "", fmt.Errorf("%s %q is malformed after rendering: %s", t.what, rendered, err)
+	}
+	return rendered, nil
+}
+
+func validateSearchBase(s string) error {
+	_, err := ldap.ParseDN(s)
+	return err
+}
+
+func validateSearchFilter(s string) error {
+	_, err := ldap.CompileFilter(s)
+	return err
+}
+
+// searchTemplates holds whichever of a search's bases and filter are templates.
+type searchTemplates struct {
+	// keyed by the unrendered base.
+	bases  map[string]*searchTemplate
+	filter *searchTemplate
+}
+
+func newSearchTemplates(bases []string, filter string) (searchTemplates, error) {
+	templates := searchTemplates{bases: make(map[string]*searchTemplate)}
+	for _, base := range bases {
+		t, err := newSearchTemplate("search", base, validateSearchBase)
+		if err != nil {
+			return templates, err
+		}
+		if t != nil {
+			templates.bases[base] = t
+		}
+	}
+	var err error
+	if templates.filter, err = newSearchTemplate("filter", filter, validateSearchFilter); err != nil {
+		return templates, err
+	}
+	return templates, nil
+}
+
+// request returns the search request to issue for this scrape; req itself if nothing is templated.
+func (t searchTemplates) request(req *ldap.SearchRequest, now time.Time) (*ldap.SearchRequest, error) {
+	base := t.bases[req.BaseDN]
+	if base == nil && t.filter == nil {
+		return req, nil
+	}
+	rendered := *req
+	var err error
+	if base != nil {
+		if rendered.BaseDN, err = base.render(now); err != nil {
+			return nil, err
+		}
+	}
+	if t.filter != nil {
+		if rendered.Filter, err = t.filter.render(now); err != nil {
+			return nil, err
+		}
+	}
+	return &rendered, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig"
+	"gopkg.in/ldap.v2"
+)
+
+// templateFuncs are the functions available to config supplied templates; sprig's,
+// plus LDAP specific helpers.
+func templateFuncs() template.FuncMap {
+	funcs := (template.FuncMap)(sprig.FuncMap())
+	for name, f := range ldapFuncs {
+		funcs[name] = f
+	}
+	// placeholder so templates parse; translators bind the real thing at execution.
+	funcs["emit"] = func(value interface{}, labels ...map[string]interface{}) (string, error) {
+		return "", fmt.Errorf("emit can only be used within translators")
+	}
+	return funcs
+}
+
+var ldapFuncs = template.FuncMap{
+	"parseDN":         ldap.ParseDN,
+	"rdn":             rdnValue,
+	"normalizeDN":     normalizeDN,
+	"generalizedTime": parseGeneralizedTime,
+	"csnTime":         parseCSN,
+	"filetime":        parseFiletime,
+	"escapeFilter":    escapeFilter,
+	"ldapBool":        parseBoolean,
+}
+
+// rdnValue returns the value of the leading RDN; cn=userRoot,cn=ldbm database is userRoot.
+func rdnValue(dn string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return "", fmt.Errorf("dn %q has no RDN", dn)
+	}
+	return parsed.RDNs[0].Attributes[0].Value, nil
+}
+
+// normalizeDN lowercases and re-escapes a DN so textually different forms of the same DN compare equal.
+func normalizeDN(dn string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	return normalizeRDNs(parsed.RDNs), nil
+}
+
+func normalizeRDNs(parsed []*ldap.RelativeDN) string {
+	rdns := make([]string, len(parsed))
+	for idx, rdn := range parsed {
+		attrs := make([]string, len(rdn.Attributes))
+		for attr_idx, attr := range rdn.Attributes {
+			attrs[attr_idx] = strings.ToLower(attr.Type) + "=" + escapeDNValue(strings.ToLower(attr.Value))
+		}
+		rdns[idx] = strings.Join(attrs, "+")
+	}
+	return strings.Join(rdns, ",")
+}
+
+// escapeDNValue escapes an attribute value per RFC 4514 2.4.
+func escapeDNValue(value string) string {
+	var b strings.Builder
+	for idx, r := range value {
+		switch {
+		case r == ',' || r == '+' || r == '"' || r == '\\' || r == '<' || r == '>' || r == ';' || r == '=':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == 0:
+			b.WriteString("\\00")
+		case (r == ' ' || r == '#') && idx == 0:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == ' ' && idx == len(value)-1:
+			b.WriteString("\\ ")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// escapeFilter escapes a value for inclusion in a search filter per RFC 4515 3.
+func escapeFilter(value string) string {
+	var b strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		switch c := value[idx]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// csn is a decoded replication change sequence number.
+type csn struct {
+	Time      time.Time
+	ReplicaID int
+	Sequence  int
+	SubSeq    int
+}
+
+// parseCSN decodes both 389 CSNs (5a4d1f5e000000040000: hex time, sequence, replica id,
+// subsequence) and OpenLDAP CSNs (20180103211642.123456Z#000000#001#000000: time, count,
+// server id, modification).
+func parseCSN(value string) (*csn, error) {
+	if strings.Contains(value, "#") {
+		parts := strings.Split(value, "#")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("%q isn't a valid CSN", value)
+		}
+		t, err := parseGeneralizedTime(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't a valid CSN: %s", value, err)
+		}
+		var fields [3]int64
+		for idx, part := range parts[1:] {
+			if fields[idx], err = strconv.ParseInt(part, 16, 64); err != nil {
+				return nil, fmt.Errorf("%q isn't a valid CSN: %s", value, err)
+			}
+		}
+		return &csn{Time: t, Sequence: int(fields[0]), ReplicaID: int(fields[1]), SubSeq: int(fields[2])}, nil
+	}
+
+	if len(value) != 20 {
+		return nil, fmt.Errorf("%q isn't a valid CSN", value)
+	}
+	var fields [4]int64
+	for idx, field := range []string{value[0:8], value[8:12], value[12:16], value[16:20]} {
+		x, err := strconv.ParseInt(field, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't a valid CSN: %s", value, err)
+		}
+		fields[idx] = x
+	}
+	return &csn{Time: time.Unix(fields[0], 0).UTC(), Sequence: int(fields[1]), ReplicaID: int(fields[2]), SubSeq: int(fields[3])}, nil
+}
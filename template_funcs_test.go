@@ -0,0 +1,178 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestRdnValue(t *testing.T) {
+	for dn, expected := range map[string]string{
+		"cn=monitor,cn=userRoot,cn=ldbm database,cn=plugins,cn=config": "monitor",
+		"cn=Directory Manager": "Directory Manager",
+		"fqdn=fake-node.realm.org,cn=computers,cn=accounts,dc=fake,dc=realm,dc=org":             "fake-node.realm.org",
+		"cn=Posix IDs,cn=Distributed Numeric Assignment Plugin,cn=plugins,cn=config":            "Posix IDs",
+		`cn=agmt\2C with comma,cn=replica,cn=dc\3Dexample\2Cdc\3Dcom,cn=mapping tree,cn=config`: "agmt, with comma",
+	} {
+		value, err := rdnValue(dn)
+		if err != nil {
+			t.Errorf("rdn(%q) failed: %s", dn, err)
+		} else if value != expected {
+			t.Errorf("rdn(%q) = %q, expected %q", dn, value, expected)
+		}
+	}
+	if _, err := rdnValue(""); err == nil {
+		t.Errorf("rdn of the root DSE should fail")
+	}
+	if _, err := rdnValue("not a dn"); err == nil {
+		t.Errorf("rdn of a malformed DN should fail")
+	}
+}
+
+func TestNormalizeDN(t *testing.T) {
+	for dn, expected := range map[string]string{
+		"cn=Directory Manager":                     "cn=directory manager",
+		"CN=Monitor,CN=userRoot, cn=LDBM Database": "cn=monitor,cn=userroot,cn=ldbm database",
+		"cn=replica,cn=dc\\3Dexample\\2Cdc\\3Dcom": "cn=replica,cn=dc\\=example\\,dc\\=com",
+		"uid=a+cn=B,dc=example,dc=com":             "uid=a+cn=b,dc=example,dc=com",
+		"":                                         "",
+	} {
+		value, err := normalizeDN(dn)
+		if err != nil {
+			t.Errorf("normalizeDN(%q) failed: %s", dn, err)
+		} else if value != expected {
+			t.Errorf("normalizeDN(%q) = %q, expected %q", dn, value, expected)
+		}
+	}
+	a, _ := normalizeDN("cn=Replica,cn=dc\\3Dexample\\2Cdc\\3Dcom")
+	b, _ := normalizeDN("CN=replica, CN=dc\\=example\\,dc\\=com")
+	if a != b {
+		t.Errorf("equivalent DNs normalized differently: %q != %q", a, b)
+	}
+}
+
+func TestGeneralizedTime(t *testing.T) {
+	for value, expected := range map[string]time.Time{
+		"20180103211642Z":        time.Date(2018, 1, 3, 21, 16, 42, 0, time.UTC),
+		"20171129175928Z":        time.Date(2017, 11, 29, 17, 59, 28, 0, time.UTC),
+		"19700101000000Z":        time.Unix(0, 0),
+		"20180103211642.5Z":      time.Date(2018, 1, 3, 21, 16, 42, 500000000, time.UTC),
+		"201801032116,5Z":        time.Date(2018, 1, 3, 21, 16, 30, 0, time.UTC),
+		"2018010321Z":            time.Date(2018, 1, 3, 21, 0, 0, 0, time.UTC),
+		"20180103211642-0500":    time.Date(2018, 1, 4, 2, 16, 42, 0, time.UTC),
+		"20180103211642.25+0130": time.Date(2018, 1, 3, 19, 46, 42, 250000000, time.UTC),
+	} {
+		parsed, err := parseGeneralizedTime(value)
+		if err != nil {
+			t.Errorf("generalizedTime(%q) failed: %s", value, err)
+		} else if !parsed.Equal(expected) {
+			t.Errorf("generalizedTime(%q) = %s, expected %s", value, parsed, expected)
+		}
+	}
+	for _, value := range []string{"", "20180103", "20180103211642", "20180230211642Z", "2018-01-03T21:16:42Z"} {
+		if _, err := parseGeneralizedTime(value); err == nil {
+			t.Errorf("generalizedTime(%q) should have failed", value)
+		}
+	}
+}
+
+func TestCSNTime(t *testing.T) {
+	for value, expected := range map[string]csn{
+		// 389, as seen in nsds50ruv and nsds5AgmtMaxCSN.
+		"5a4d1f5e000000040000": {Time: time.Unix(0x5a4d1f5e, 0), Sequence: 0, ReplicaID: 4},
+		"5a4d2a1e000300070001": {Time: time.Unix(0x5a4d2a1e, 0), Sequence: 3, ReplicaID: 7, SubSeq: 1},
+		// openldap contextCSN.
+		"20180103211642.123456Z#000002#001#000000": {Time: time.Date(2018, 1, 3, 21, 16, 42, 123456000, time.UTC), Sequence: 2, ReplicaID: 1},
+	} {
+		parsed, err := parseCSN(value)
+		if err != nil {
+			t.Errorf("csnTime(%q) failed: %s", value, err)
+			continue
+		}
+		if !parsed.Time.Equal(expected.Time) || parsed.Sequence != expected.Sequence || parsed.ReplicaID != expected.ReplicaID || parsed.SubSeq != expected.SubSeq {
+			t.Errorf("csnTime(%q) = %+v, expected %+v", value, *parsed, expected)
+		}
+	}
+	for _, value := range []string{"", "5a4d1f5e", "zz4d1f5e000000040000", "20180103211642Z#000000#001"} {
+		if _, err := parseCSN(value); err == nil {
+			t.Errorf("csnTime(%q) should have failed", value)
+		}
+	}
+}
+
+func TestFiletime(t *testing.T) {
+	for value, expected := range map[string]time.Time{
+		"132223104000000000": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"116444736000000000": time.Unix(0, 0),
+		"131594878021234567": time.Date(2018, 1, 3, 21, 16, 42, 123456700, time.UTC),
+	} {
+		parsed, err := parseFiletime(value)
+		if err != nil {
+			t.Errorf("filetime(%q) failed: %s", value, err)
+		} else if !parsed.Equal(expected) {
+			t.Errorf("filetime(%q) = %s, expected %s", value, parsed, expected)
+		}
+	}
+	if _, err := parseFiletime("never"); err == nil {
+		t.Errorf("filetime of a non number should fail")
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	for value, expected := range map[string]string{
+		"cn=Directory Manager": "cn=Directory Manager",
+		"a*(b)\\c":             `a\2a\28b\29\5cc`,
+		"nul\x00":              `nul\00`,
+		"host$":                "host$",
+	} {
+		if escaped := escapeFilter(value); escaped != expected {
+			t.Errorf("escapeFilter(%q) = %q, expected %q", value, escaped, expected)
+		}
+	}
+}
+
+func TestLdapBool(t *testing.T) {
+	for value, expected := range map[string]bool{
+		"TRUE": true, "FALSE": false, "on": true, "off": false, "1": true, "0": false,
+	} {
+		parsed, err := parseBoolean(value)
+		if err != nil {
+			t.Errorf("ldapBool(%q) failed: %s", value, err)
+		} else if parsed != expected {
+			t.Errorf("ldapBool(%q) = %t, expected %t", value, parsed, expected)
+		}
+	}
+	if _, err := parseBoolean("maybe"); err == nil {
+		t.Errorf("ldapBool of an unknown value should fail")
+	}
+}
+
+func TestTranslatorTemplateFuncs(t *testing.T) {
+	var translator templateString
+	if err := yaml.Unmarshal([]byte(`|
+  {{ range .values }}{{ with splitList ":" . }}
+    {{ emit (generalizedTime (index . 1)).Unix (dict "bind" (rdn (index . 5)) "filter" (printf "(uid=%s)" (escapeFilter (index . 5)))) }}
+  {{ end }}{{ end }}`), &translator); err != nil {
+		t.Fatal(err)
+	}
+	results, err := (&templateTranslator{template: translator.template}).translate(&scrapeContext{}, []string{
+		"95:20171129175928Z:48926:48926:-:cn=Directory Manager:0:0:0",
+		"64:20171129175928Z:6:6:-:cn=Directory Manager:0:0:0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Value != 1511978368 {
+		t.Errorf("expected value 1511978368, got %f", results[0].Value)
+	}
+	if results[0].Labels["bind"] != "Directory Manager" {
+		t.Errorf("expected bind label 'Directory Manager', got %q", results[0].Labels["bind"])
+	}
+	if results[0].Labels["filter"] != `(uid=cn=Directory Manager)` {
+		t.Errorf("unexpected filter label %q", results[0].Labels["filter"])
+	}
+}
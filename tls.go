@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	ber "gopkg.in/asn1-ber.v1"
+	"gopkg.in/ldap.v2"
+)
+
+// tlsVersionNames and tlsCipherNames exist since tls only names these itself as of go 1.14.
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+var tlsCipherNames = map[uint16]string{
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA:            "TLS_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA:            "TLS_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256:         "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384:         "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:   "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305:    "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305",
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305:  "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305",
+	tls.TLS_AES_128_GCM_SHA256:                  "TLS_AES_128_GCM_SHA256",
+	tls.TLS_AES_256_GCM_SHA384:                  "TLS_AES_256_GCM_SHA384",
+	tls.TLS_CHACHA20_POLY1305_SHA256:            "TLS_CHACHA20_POLY1305_SHA256",
+	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA:           "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA:     "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA256:         "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256:   "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+}
+
+func tlsName(names map[uint16]string, id uint16) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", id)
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	var names []string
+	for id, name := range tlsVersionNames {
+		if strings.TrimPrefix(name, "TLS ") == version {
+			return id, nil
+		}
+		names = append(names, strings.TrimPrefix(name, "TLS "))
+	}
+	sort.Strings(names)
+	return 0, fmt.Errorf("TLS version %s is unknown; supported options are %s", version, strings.Join(names, ", "))
+}
+
+// parseCipherSuites parses a comma separated list of suite names as tls names them.
+func parseCipherSuites(suites string) ([]uint16, error) {
+	var ids []uint16
+	for _, suite := range strings.Split(suites, ",") {
+		suite = strings.TrimSpace(suite)
+		if suite == "" {
+			continue
+		}
+		found := false
+		for id, name := range tlsCipherNames {
+			if name == suite {
+				ids = append(ids, id)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("TLS cipher suite %s is unknown", suite)
+		}
+	}
+	return ids, nil
+}
+
+// parseSPKIPins parses a comma separated list of base64 SHA-256 hashes of certificates'
+// SubjectPublicKeyInfo, the form HPKP used.
+func parseSPKIPins(pins string) ([][]byte, error) {
+	var hashes [][]byte
+	for _, pin := range strings.Split(pins, ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("SPKI pin %s isn't a base64 encoded SHA-256 hash", pin)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// verifySPKIPins builds a VerifyPeerCertificate callback accepting the server if the leaf
+// certificate it presents has a pinned public key.  It replaces CA verification, so it's
+// usable for self signed certificates.  Only the leaf is checked since the handshake proves
+// the server holds the leaf's key, whereas the rest of an unverified chain is whatever the
+// server chose to send.
+func verifySPKIPins(pins [][]byte) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("the server presented no certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(hash[:], pin) {
+				return nil
+			}
+		}
+		return fmt.Errorf("the server's certificate doesn't match a pinned public key")
+	}
+}
+
+// dialTLS establishes TLS over conn itself rather than leaving it to the ldap client, which
+// discards the handshake's state.  If startTLS is set, conn is first upgraded via the StartTLS
+// extended operation; otherwise it's ldaps.
+func dialTLS(conn net.Conn, config *tls.Config, startTLS bool) (*ldap.Conn, *tls.ConnectionState, error) {
+	if startTLS {
+		if err := requestStartTLS(conn); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	tls_conn := tls.Client(conn, config)
+	if err := tls_conn.Handshake(); err != nil {
+		conn.Close()
+		return nil, nil, ldap.NewError(ldap.ErrorNetwork, err)
+	}
+	state := tls_conn.ConnectionState()
+	client := ldap.NewConn(tls_conn, true)
+	client.Start()
+	return client, &state, nil
+}
+
+// requestStartTLS sends the StartTLS extended operation (RFC 4511 4.14) and awaits the response.
+func requestStartTLS(conn net.Conn) error {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Request")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 1, "MessageID"))
+	request := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationExtendedRequest, nil, "Start TLS")
+	request.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, "1.3.6.1.4.1.1466.20037", "TLS Extended Command"))
+	packet.AppendChild(request)
+	if _, err := conn.Write(packet.Bytes()); err != nil {
+		return ldap.NewError(ldap.ErrorNetwork, err)
+	}
+	response, err := ber.ReadPacket(conn)
+	if err != nil {
+		return ldap.NewError(ldap.ErrorNetwork, err)
+	}
+	if len(response.Children) < 2 || len(response.Children[1].Children) < 3 {
+		return ldap.NewError(ldap.ErrorUnexpectedResponse, fmt.Errorf("malformed StartTLS response"))
+	}
+	result := response.Children[1].Children
+	code, ok := result[0].Value.(int64)
+	if !ok {
+		return ldap.NewError(ldap.ErrorUnexpectedResponse, fmt.Errorf("malformed StartTLS result code"))
+	}
+	if code != ldap.LDAPResultSuccess {
+		message, _ := result[2].Value.(string)
+		return ldap.NewError(uint8(code), fmt.Errorf("StartTLS refused: %s", message))
+	}
+	return nil
+}
+
+// tlsCollector exports the certificates and parameters of the most recent handshake with
+// the server, so an expiring certificate can be alerted on.  The exporter's connection is
+// long lived, so check handshakes afresh once interval has passed, which notices a renewed
+// certificate.  If verification isn't enforced, as with -ldap.tls.skip-verify, it's checked
+// against the CAs anyway so it's still known.
+type tlsCollector struct {
+	config     *tls.Config
+	serverName string
+	enforced   bool
+	check      func() (*tls.ConnectionState, error)
+	interval   time.Duration
+
+	lock     sync.Mutex
+	checked  time.Time
+	state    *tls.ConnectionState
+	verified bool
+
+	notAfter   *prometheus.Desc
+	connection *prometheus.Desc
+	verify     *prometheus.Desc
+}
+
+func newTLSCollector(config *tls.Config, serverName string, enforced bool, check func() (*tls.ConnectionState, error), interval time.Duration) *tlsCollector {
+	return &tlsCollector{
+		config:     config,
+		serverName: serverName,
+		enforced:   enforced,
+		check:      check,
+		interval:   interval,
+		notAfter: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tls", "cert_not_after_seconds"),
+			"When the certificate the server presented expires, for the leaf and each element of the chain it sent.",
+			[]string{"subject", "issuer", "serial"}, nil,
+		),
+		connection: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tls", "connection_info"),
+			"The TLS version and cipher suite negotiated with the server; always 1.",
+			[]string{"version", "cipher"}, nil,
+		),
+		verify: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tls", "verified"),
+			"Whether the server's certificate verified against the configured CA for the expected name, or matched a pinned public key.",
+			nil, nil,
+		),
+	}
+}
+
+// record replaces the handshake exported; it's safe to call with the state of a plaintext connection.
+func (c *tlsCollector) record(state *tls.ConnectionState) {
+	if state == nil {
+		return
+	}
+	verified := c.enforced
+	if !verified && len(state.PeerCertificates) != 0 {
+		opts := x509.VerifyOptions{
+			Roots:         c.config.RootCAs,
+			DNSName:       c.serverName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(opts)
+		verified = err == nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.checked = time.Now()
+	c.state = state
+	c.verified = verified
+}
+
+// refresh handshakes afresh if the last handshake is older than the interval; on failure
+// the last handshake continues to be exported.
+func (c *tlsCollector) refresh() {
+	c.lock.Lock()
+	stale := time.Since(c.checked) >= c.interval
+	if stale {
+		// claimed now so concurrent scrapes don't check as well.
+		c.checked = time.Now()
+	}
+	c.lock.Unlock()
+	if !stale {
+		return
+	}
+	state, err := c.check()
+	if err != nil {
+		log.Errorf("TLS check of the server failed: %s", err)
+		return
+	}
+	c.record(state)
+}
+
+func (c *tlsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.notAfter
+	ch <- c.connection
+	ch <- c.verify
+}
+
+func (c *tlsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.refresh()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.state == nil {
+		return
+	}
+	for _, cert := range c.state.PeerCertificates {
+		ch <- prometheus.MustNewConstMetric(c.notAfter, prometheus.GaugeValue, float64(cert.NotAfter.Unix()), cert.Subject.String(), cert.Issuer.String(), cert.SerialNumber.String())
+	}
+	ch <- prometheus.MustNewConstMetric(c.connection, prometheus.GaugeValue, 1, tlsName(tlsVersionNames, c.state.Version), tlsName(tlsCipherNames, c.state.CipherSuite))
+	verified := float64(0)
+	if c.verified {
+		verified = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.verify, prometheus.GaugeValue, verified)
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
+var invalidMetricNameChars = regexp.MustCompile("[^a-zA-Z0-9_:]")
+
 func checkOverflow(m map[string]interface{}, ctx string) error {
 	if len(m) > 0 {
 		var keys []string
@@ -15,3 +18,16 @@ func checkOverflow(m map[string]interface{}, ctx string) error {
 	}
 	return nil
 }
+
+func sanitizeMetricName(name string) string {
+	return invalidMetricNameChars.ReplaceAllString(name, "_")
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// valueParser converts a raw LDAP attribute value into a sample value.
+type valueParser func(string) (float64, error)
+
+// the declarative value_format choices for metrics.
+var valueFormats = map[string]valueParser{
+	"number":           parseNumber,
+	"generalized_time": parseGeneralizedTimeValue,
+	"windows_filetime": parseFiletimeValue,
+	"boolean":          parseBooleanValue,
+	"duration":         parseDurationValue,
+	"hex":              parseHexValue,
+	"size":             parseSizeValue,
+}
+
+func lookupValueFormat(format string) (valueParser, error) {
+	parser, ok := valueFormats[format]
+	if !ok {
+		var names []string
+		for name := range valueFormats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("value_format %s is unknown; supported options are %s", format, strings.Join(names, ", "))
+	}
+	return parser, nil
+}
+
+// valueConverter applies a metric's value_format, age, and scale to raw values.
+type valueConverter struct {
+	parse valueParser
+	scale float64
+	// if set, values are timestamps and the seconds elapsed since then is returned.
+	age bool
+}
+
+// newValueConverter builds a converter for the given format, using default_parser if
+// no format was configured.
+func newValueConverter(format string, scale float64, age bool, default_parser valueParser) (*valueConverter, error) {
+	parser := default_parser
+	if age && format == "" {
+		format = "generalized_time"
+	}
+	if format != "" {
+		var err error
+		if parser, err = lookupValueFormat(format); err != nil {
+			return nil, err
+		}
+	}
+	if scale == 0 {
+		scale = 1
+	}
+	return &valueConverter{parse: parser, scale: scale, age: age}, nil
+}
+
+func (v *valueConverter) convert(ctx *scrapeContext, value string) (float64, error) {
+	x, err := v.parse(value)
+	if err != nil {
+		return 0, err
+	}
+	return v.adjust(ctx, x), nil
+}
+
+// adjust applies age and scale to an already parsed value, for example translator output.
+func (v *valueConverter) adjust(ctx *scrapeContext, value float64) float64 {
+	if v.age {
+		value = unixSeconds(ctx.now) - value
+	}
+	return value * v.scale
+}
+
+func parseNumber(value string) (float64, error) {
+	return strconv.ParseFloat(value, 64)
+}
+
+func parseUnsigned(value string) (float64, error) {
+	x, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(x), nil
+}
+
+// RFC 4517 3.3.13; minutes and seconds are optional, and a fraction applies to the
+// last unit given.
+var generalizedTimeRegex = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})(\d{2})(\d{2})?(\d{2})?(?:[.,](\d+))?(Z|[+-]\d{2}(?:\d{2})?)$`)
+
+func parseGeneralizedTime(value string) (time.Time, error) {
+	m := generalizedTimeRegex.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("%q isn't a valid GeneralizedTime", value)
+	}
+	atoi := func(s string) int {
+		if s == "" {
+			return 0
+		}
+		x, _ := strconv.Atoi(s)
+		return x
+	}
+
+	// time.Date silently normalizes out of range values, so check them up front.
+	if atoi(m[4]) > 23 || atoi(m[5]) > 59 {
+		return time.Time{}, fmt.Errorf("%q isn't a valid GeneralizedTime: time is out of range", value)
+	}
+	location := time.UTC
+	if zone := m[8]; zone != "Z" {
+		if atoi(zone[1:3]) > 23 || atoi(zone[3:]) > 59 {
+			return time.Time{}, fmt.Errorf("%q isn't a valid GeneralizedTime: offset is out of range", value)
+		}
+		offset := atoi(zone[1:3])*3600 + atoi(zone[3:])*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		location = time.FixedZone(zone, offset)
+	}
+	t := time.Date(atoi(m[1]), time.Month(atoi(m[2])), atoi(m[3]), atoi(m[4]), atoi(m[5]), atoi(m[6]), 0, location)
+	if t.Month() != time.Month(atoi(m[2])) || t.Day() != atoi(m[3]) {
+		return time.Time{}, fmt.Errorf("%q isn't a valid GeneralizedTime: date is out of range", value)
+	}
+
+	if m[7] != "" {
+		fraction, err := strconv.ParseFloat("0."+m[7], 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q isn't a valid GeneralizedTime: %s", value, err)
+		}
+		unit := time.Hour
+		if m[6] != "" {
+			unit = time.Second
+		} else if m[5] != "" {
+			unit = time.Minute
+		}
+		t = t.Add(time.Duration(fraction * float64(unit)))
+	}
+	return t, nil
+}
+
+func parseGeneralizedTimeValue(value string) (float64, error) {
+	t, err := parseGeneralizedTime(value)
+	if err != nil {
+		return 0, err
+	}
+	return unixSeconds(t), nil
+}
+
+// unixSeconds avoids UnixNano since it overflows for the far past values LDAP uses as sentinels.
+func unixSeconds(t time.Time) float64 {
+	return float64(t.Unix()) + float64(t.Nanosecond())/float64(time.Second)
+}
+
+// seconds between the windows FILETIME epoch (1601-01-01) and the unix epoch.
+const filetimeEpochOffset = 11644473600
+
+// parseFiletime converts AD's 100ns intervals since 1601 (pwdLastSet, lastLogonTimestamp, etc).
+func parseFiletime(value string) (time.Time, error) {
+	x, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q isn't a valid windows FILETIME: %s", value, err)
+	}
+	return time.Unix(x/1e7-filetimeEpochOffset, (x%1e7)*100).UTC(), nil
+}
+
+func parseFiletimeValue(value string) (float64, error) {
+	t, err := parseFiletime(value)
+	if err != nil {
+		return 0, err
+	}
+	return unixSeconds(t), nil
+}
+
+// parseBoolean accepts the RFC 4517 TRUE/FALSE along with the on/off and yes/no
+// conventions config attributes tend to use.
+func parseBoolean(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "on", "yes", "1":
+		return true, nil
+	case "false", "off", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q isn't a recognized boolean", value)
+}
+
+func parseBooleanValue(value string) (float64, error) {
+	b, err := parseBoolean(value)
+	if err != nil || !b {
+		return 0, err
+	}
+	return 1, nil
+}
+
+var durationDayRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)([dw])`)
+
+// parseDuration accepts go durations extended with d and w units; a bare number is seconds.
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if x, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(x * float64(time.Second)), nil
+	}
+	expanded := durationDayRegex.ReplaceAllStringFunc(value, func(s string) string {
+		m := durationDayRegex.FindStringSubmatch(s)
+		hours, _ := strconv.ParseFloat(m[1], 64)
+		if m[2] == "w" {
+			hours *= 7
+		}
+		return strconv.FormatFloat(hours*24, 'f', -1, 64) + "h"
+	})
+	d, err := time.ParseDuration(expanded)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a valid duration: %s", value, err)
+	}
+	return d, nil
+}
+
+func parseDurationValue(value string) (float64, error) {
+	d, err := parseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return d.Seconds(), nil
+}
+
+func parseHexValue(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X") {
+		value = value[2:]
+	}
+	x, err := strconv.ParseUint(value, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't valid hex: %s", value, err)
+	}
+	return float64(x), nil
+}
+
+var sizeRegex = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*([a-zA-Z]*)$`)
+
+// sizes use SI units for KB/MB/etc, and IEC units for KiB/MiB/etc.  A bare K/M/G/T/P is
+// treated as binary since that's what most server configuration means by it.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1e12,
+	"tib": 1 << 40,
+	"p":   1 << 50,
+	"pb":  1e15,
+	"pib": 1 << 50,
+}
+
+func parseSizeValue(value string) (float64, error) {
+	m := sizeRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, fmt.Errorf("%q isn't a valid size", value)
+	}
+	multiplier, ok := sizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("%q isn't a valid size: unknown unit %s", value, m[2])
+	}
+	x, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(x * multiplier), nil
+}
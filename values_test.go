@@ -0,0 +1,153 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValueFormats(t *testing.T) {
+	for format, values := range map[string]map[string]float64{
+		"generalized_time": {
+			"19700101000000Z":     0,
+			"20180103211642Z":     1515014202,
+			"20180103211642.25Z":  1515014202.25,
+			"201801032116Z":       1515014160,
+			"201801032116.5Z":     1515014190,
+			"2018010321Z":         1515013200,
+			"2018010321,25Z":      1515014100,
+			"20180103211642+0200": 1515007002,
+			"20180103211642-0530": 1515034002,
+			"20180103211642.5-05": 1515032202.5,
+			"16010101000000Z":     -11644473600,
+		},
+		"windows_filetime": {
+			"116444736000000000": 0,
+			"131592222520000000": 1514748652,
+			"116444736005000000": 0.5,
+			"0":                  -11644473600,
+		},
+		"size": {
+			"512":     512,
+			"512b":    512,
+			"1k":      1 << 10,
+			"1KB":     1e3,
+			"1KiB":    1 << 10,
+			"1.5 M":   1.5 * (1 << 20),
+			"2MB":     2e6,
+			"1g":      1 << 30,
+			"1GB":     1e9,
+			"1t":      1 << 40,
+			"1TiB":    1 << 40,
+			"1p":      1 << 50,
+			"1PB":     1e15,
+			"1pib":    1 << 50,
+			" 10 mib": 10 << 20,
+		},
+		"duration": {
+			"30":     30,
+			"1.5":    1.5,
+			"90s":    90,
+			"1h30m":  5400,
+			"1d":     86400,
+			"1.5d":   129600,
+			"2w":     1209600,
+			"1w1d1h": 694800,
+		},
+		"hex": {
+			"ff":    255,
+			"0xff":  255,
+			"0X10":  16,
+			"DEAD":  57005,
+			" 0x0 ": 0,
+		},
+		"boolean": {
+			"TRUE":  1,
+			"FALSE": 0,
+			"on":    1,
+			"off":   0,
+			"Yes":   1,
+			"no":    0,
+			"1":     1,
+			"0":     0,
+		},
+	} {
+		parser, err := lookupValueFormat(format)
+		if err != nil {
+			t.Errorf("lookupValueFormat(%q) failed: %s", format, err)
+			continue
+		}
+		for value, expected := range values {
+			result, err := parser(value)
+			if err != nil {
+				t.Errorf("%s(%q) failed: %s", format, value, err)
+			} else if result != expected {
+				t.Errorf("%s(%q) = %v, expected %v", format, value, result, expected)
+			}
+		}
+	}
+	for format, values := range map[string][]string{
+		"generalized_time": {
+			"",
+			"20180103211642",
+			"20181303211642Z",
+			"20180230211642Z",
+			"20180103241642Z",
+			"20180103216042Z",
+			"20180103211642+2400",
+			"20180103211642+0260",
+		},
+		"windows_filetime": {"", "1.5", "not a number"},
+		"size":             {"", "1x", "1 zb", "k"},
+		"duration":         {"", "1y", "d"},
+		"hex":              {"", "0x", "fg"},
+		"boolean":          {"", "maybe", "2"},
+	} {
+		parser, _ := lookupValueFormat(format)
+		for _, value := range values {
+			if _, err := parser(value); err == nil {
+				t.Errorf("%s(%q) should fail", format, value)
+			}
+		}
+	}
+	if _, err := lookupValueFormat("nope"); err == nil {
+		t.Errorf("lookupValueFormat of an unknown format should fail")
+	}
+}
+
+func TestValueConverter(t *testing.T) {
+	ctx := &scrapeContext{now: time.Date(2018, 1, 3, 21, 16, 42, 0, time.UTC)}
+	for _, tc := range []struct {
+		format   string
+		scale    float64
+		age      bool
+		value    string
+		expected float64
+	}{
+		{"", 0, false, "42", 42},
+		{"", 0.001, false, "1500", 1.5},
+		{"size", 0, false, "1k", 1024},
+		{"size", 1.0 / 1024, false, "1m", 1024},
+		{"duration", 1000, false, "1.5s", 1500},
+		{"boolean", 5, false, "TRUE", 5},
+		// ages default to generalized_time.
+		{"", 0, true, "20180103201642Z", 3600},
+		{"", 1.0 / 60, true, "20180103211542Z", 1},
+		{"generalized_time", 0, true, "20180103211652Z", -10},
+		{"windows_filetime", 0, true, "131594878020000000", 0},
+	} {
+		converter, err := newValueConverter(tc.format, tc.scale, tc.age, parseNumber)
+		if err != nil {
+			t.Errorf("newValueConverter(%q, %v, %v) failed: %s", tc.format, tc.scale, tc.age, err)
+			continue
+		}
+		result, err := converter.convert(ctx, tc.value)
+		if err != nil {
+			t.Errorf("convert(%q) with format %q, scale %v, and age %v failed: %s", tc.value, tc.format, tc.scale, tc.age, err)
+		} else if result != tc.expected {
+			t.Errorf("convert(%q) with format %q, scale %v, and age %v = %v, expected %v", tc.value, tc.format, tc.scale, tc.age, result, tc.expected)
+		}
+	}
+	if _, err := newValueConverter("nope", 1, false, parseNumber); err == nil {
+		t.Errorf("newValueConverter with an unknown format should fail")
+	}
+}